@@ -9,6 +9,7 @@ import (
 	"prostic/internal/config"
 	"prostic/internal/restic"
 	"prostic/internal/server"
+	repositoryservice "prostic/internal/service/repository"
 )
 
 var (
@@ -57,16 +58,88 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "repo",
+				Usage: "Manage restic repositories",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "status",
+						Usage: "Show whether a repository is reachable and initialized",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "repository",
+								Usage: "Name of the configured repository",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							status, err := repositoryservice.GetStatus(c.Context, c.String("repository"))
+							if err != nil {
+								return cli.Exit("Failed to check repository: "+err.Error(), 1)
+							}
+
+							fmt.Printf("%s: %s\n", status.Repository, status.Status)
+							if status.Message != "" {
+								fmt.Println(status.Message)
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "init",
+						Usage: "Initialize a configured repository",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "repository",
+								Usage: "Name of the configured repository",
+							},
+							&cli.StringFlag{
+								Name:  "compression",
+								Value: repositoryservice.CompressionAuto,
+								Usage: "Compression support: auto, max or off",
+							},
+							&cli.StringFlag{
+								Name:  "copy-chunker-params-from",
+								Usage: "Copy chunker parameters from this configured repository",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							output, err := repositoryservice.Init(c.Context, repositoryservice.InitOptions{
+								Repository:            c.String("repository"),
+								Compression:           c.String("compression"),
+								CopyChunkerParamsFrom: c.String("copy-chunker-params-from"),
+							})
+							fmt.Print(output)
+							if err != nil {
+								return cli.Exit("Failed to initialize repository: "+err.Error(), 1)
+							}
+							return nil
+						},
+					},
+				},
+			},
 			{
 				Name:      "restic",
 				Usage:     "Run a raw restic command (e.g., restic snapshots)",
 				ArgsUsage: "[restic args...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "repository",
+						Usage: "Name of the configured repository to run against",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					args := c.Args().Slice()
 					if len(args) == 0 {
 						return cli.Exit("No restic arguments provided", 1)
 					}
-					err := restic.RunResticCommand(true, args...)
+					runner, err := restic.ForRepository(c.String("repository"))
+					if err != nil {
+						return cli.Exit(err.Error(), 1)
+					}
+					runner = runner.WithStderr(func(line string) {
+						_, _ = fmt.Fprintln(os.Stderr, line)
+					})
+					err = runner.Run(c.Context, os.Stdout, args...)
 					if err != nil {
 						return cli.Exit("Restic command failed: "+err.Error(), 1)
 					}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"prostic/internal/util"
+)
+
+func validateCheck(c *Config) error {
+	if c.Check.Cron != "" {
+		if _, err := util.CronMatches(c.Check.Cron, time.Now()); err != nil {
+			return fmt.Errorf("invalid cron expression of check")
+		}
+	}
+	if c.Check.ReadDataPercent < 0 || c.Check.ReadDataPercent > 100 {
+		return fmt.Errorf("check read_data_percent must be between 0 and 100")
+	}
+
+	for _, name := range c.Check.Repositories {
+		found := false
+		for _, repository := range c.Repositories {
+			if repository.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("check repository %q is not defined", name)
+		}
+	}
+
+	return nil
+}
+
+// CheckRepositories returns the repositories covered by scheduled checks.
+func CheckRepositories() []string {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Check.Repositories) > 0 {
+		return cfg.Check.Repositories
+	}
+
+	return RepositoryNames()
+}
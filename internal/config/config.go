@@ -1,29 +1,70 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type VM struct {
-	Name  string   `yaml:"name"`
-	ID    int      `yaml:"id"`
-	IsVM  bool     `yaml:"is_vm"`
-	Disks []string `yaml:"disks"`
+	Name       string   `yaml:"name"`
+	ID         int      `yaml:"id"`
+	IsVM       bool     `yaml:"is_vm"`
+	Disks      []string `yaml:"disks"`
+	Repository string   `yaml:"repository"`
+	StaleAfter string   `yaml:"stale_after"`
 }
 
+// Restic holds settings shared by all repositories. Any other keys are
+// treated as environment variables of an implicit "default" repository when
+// no repositories are configured.
 type Restic struct {
+	Binary  string            `yaml:"binary"`
+	Timeout string            `yaml:"timeout"`
 	EnvVars map[string]string `yaml:",inline"`
 }
+type Repository struct {
+	Name string            `yaml:"name"`
+	Env  map[string]string `yaml:"env"`
+}
 type Backup struct {
+	Repository           string `yaml:"repository"`
+	RemoveLocksOlderThan string `yaml:"remove_locks_older_than"`
+	StaleAfter           string `yaml:"stale_after"`
+}
+type Replication struct {
+	Name        string   `yaml:"name"`
+	Source      string   `yaml:"source"`
+	Targets     []string `yaml:"targets"`
+	AfterBackup bool     `yaml:"after_backup"`
+	Cron        string   `yaml:"cron"`
+	MaxAge      string   `yaml:"max_age"`
+}
+type Check struct {
+	Cron            string   `yaml:"cron"`
+	Repositories    []string `yaml:"repositories"`
+	ReadDataPercent int      `yaml:"read_data_percent"`
+}
+
+// Metrics protects /metrics with a bearer token. Without a token the
+// endpoint is only served when Public is set.
+type Metrics struct {
+	Token  string `yaml:"token"`
+	Public bool   `yaml:"public"`
 }
 type Config struct {
-	VMs    []VM   `yaml:"vms"`
-	Restic Restic `yaml:"restic"`
-	Backup Backup `yaml:"backup"`
+	VMs           []VM          `yaml:"vms"`
+	Restic        Restic        `yaml:"restic"`
+	Repositories  []Repository  `yaml:"repositories"`
+	Backup        Backup        `yaml:"backup"`
+	Replications  []Replication `yaml:"replication"`
+	Check         Check         `yaml:"check"`
+	Notifications Notifications `yaml:"notifications"`
+	Metrics       Metrics       `yaml:"metrics"`
 }
 
 var cfg *Config
@@ -39,12 +80,89 @@ func Load(path string) error {
 	if err := yaml.Unmarshal(data, &c); err != nil {
 		return err
 	}
+	if err := normalizeRepositories(&c); err != nil {
+		return err
+	}
+	if err := resolveSecrets(&c); err != nil {
+		return err
+	}
+	if err := validateReplications(&c); err != nil {
+		return err
+	}
+	if err := validateCheck(&c); err != nil {
+		return err
+	}
+	if err := validateNotifications(&c); err != nil {
+		return err
+	}
+	if c.Restic.Timeout != "" {
+		if _, err := time.ParseDuration(c.Restic.Timeout); err != nil {
+			return fmt.Errorf("invalid restic timeout: %w", err)
+		}
+	}
+	if c.Backup.RemoveLocksOlderThan != "" {
+		if _, err := time.ParseDuration(c.Backup.RemoveLocksOlderThan); err != nil {
+			return fmt.Errorf("invalid remove_locks_older_than: %w", err)
+		}
+	}
+	if c.Backup.StaleAfter != "" {
+		if _, err := time.ParseDuration(c.Backup.StaleAfter); err != nil {
+			return fmt.Errorf("invalid stale_after: %w", err)
+		}
+	}
+	for _, vm := range c.VMs {
+		if vm.StaleAfter != "" {
+			if _, err := time.ParseDuration(vm.StaleAfter); err != nil {
+				return fmt.Errorf("invalid stale_after of vm %d: %w", vm.ID, err)
+			}
+		}
+	}
+
+	warnIfWorldReadable(path)
 
 	cfg = &c
 	configPath = path
 	return nil
 }
 
+// LockThreshold returns the age after which locks are removed before a
+// backup; zero disables automatic removal.
+func (b Backup) LockThreshold() time.Duration {
+	if b.RemoveLocksOlderThan == "" {
+		return 0
+	}
+
+	threshold, _ := time.ParseDuration(b.RemoveLocksOlderThan)
+	return threshold
+}
+
+// DefaultStaleAfter is used when stale_after is not configured.
+const DefaultStaleAfter = 48 * time.Hour
+
+// StaleThreshold returns the age after which the last complete backup of a
+// guest counts as stale.
+func (b Backup) StaleThreshold() time.Duration {
+	threshold, err := time.ParseDuration(b.StaleAfter)
+	if b.StaleAfter == "" || err != nil {
+		return DefaultStaleAfter
+	}
+
+	return threshold
+}
+
+// StaleThreshold returns the maximum acceptable backup age of vm. A guest
+// setting wins over the backup default.
+func StaleThreshold(vm VM) time.Duration {
+	if threshold, err := time.ParseDuration(vm.StaleAfter); vm.StaleAfter != "" && err == nil {
+		return threshold
+	}
+	if cfg == nil {
+		return DefaultStaleAfter
+	}
+
+	return cfg.Backup.StaleThreshold()
+}
+
 func Get() *Config {
 	return cfg
 }
@@ -98,3 +216,34 @@ func SnapshotExists(snapshotType string, vmID *int, srcFile string) bool {
 
 	return false
 }
+
+// ConfiguredItem is a disk or config file of a guest that is backed up.
+type ConfiguredItem struct {
+	VMID         int    `json:"vmid"`
+	SnapshotType string `json:"snapshotType"`
+	SrcFile      string `json:"srcFile"`
+}
+
+// PlannedItem is an item a backup run sets out to back up and the repository
+// it goes to. Runs recorded before the repository was stored leave it empty.
+type PlannedItem struct {
+	ConfiguredItem
+	Repository string `json:"repository,omitempty"`
+}
+
+// ConfiguredItems lists every item SnapshotExists accepts.
+func ConfiguredItems() []ConfiguredItem {
+	if cfg == nil {
+		return nil
+	}
+
+	items := make([]ConfiguredItem, 0)
+	for _, vm := range cfg.VMs {
+		for _, disk := range vm.Disks {
+			items = append(items, ConfiguredItem{VMID: vm.ID, SnapshotType: "disk", SrcFile: disk})
+		}
+		items = append(items, ConfiguredItem{VMID: vm.ID, SnapshotType: "config", SrcFile: ConfigFilePath(vm)})
+	}
+
+	return items
+}
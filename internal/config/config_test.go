@@ -0,0 +1,20 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+// The shipped sample has to load on a fresh install without any variables.
+func TestSampleConfigLoads(t *testing.T) {
+	// restored when the test ends
+	t.Setenv("RESTIC_PASSWORD", "")
+	os.Unsetenv("RESTIC_PASSWORD")
+
+	if err := Load("../../config.yaml"); err != nil {
+		t.Fatalf("sample config does not load: %v", err)
+	}
+	if repository := FindRepository("local"); repository == nil || repository.Env["RESTIC_PASSWORD"] == "" {
+		t.Errorf("sample repository = %+v", repository)
+	}
+}
@@ -0,0 +1,364 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"prostic/internal/util"
+)
+
+const (
+	NotifyOnFailure = "failure"
+	NotifyAlways    = "always"
+)
+
+const (
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "tls"
+	EmailTLSNone     = "none"
+)
+
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+const (
+	PushNtfy   = "ntfy"
+	PushGotify = "gotify"
+	PushSlack  = "slack"
+	PushMatrix = "matrix"
+)
+
+const DefaultWebhookRetries = 3
+
+const DefaultDigestTime = "08:00"
+
+type Notifications struct {
+	Webhooks []Webhook `yaml:"webhooks"`
+	Email    []Email   `yaml:"email"`
+	Push     []Push    `yaml:"push"`
+}
+
+// Webhook sends events as HTTP requests. Body is a text/template executed
+// with the event; without it the event is sent as JSON.
+type Webhook struct {
+	Name    string            `yaml:"name"`
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Secret signs the body with HMAC-SHA256.
+	Secret  string   `yaml:"secret"`
+	Filter  string   `yaml:"filter"`
+	Events  []string `yaml:"events"`
+	Retries *int     `yaml:"retries"`
+	Timeout string   `yaml:"timeout"`
+}
+
+// Email sends a report after every backup run, or a digest of all runs
+// once a day or week when Digest is set.
+type Email struct {
+	Name     string `yaml:"name"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// TLS is starttls (default), tls for implicit TLS or none.
+	TLS    string   `yaml:"tls"`
+	From   string   `yaml:"from"`
+	To     []string `yaml:"to"`
+	Filter string   `yaml:"filter"`
+	Digest string   `yaml:"digest"`
+	// DigestTime is the local time the digest is sent at, weekly digests go
+	// out on Mondays.
+	DigestTime string `yaml:"digest_time"`
+	Retries    *int   `yaml:"retries"`
+	Timeout    string `yaml:"timeout"`
+}
+
+// Push sends formatted messages to a push or chat service. URL is the ntfy
+// topic URL, the Gotify server, the Slack-compatible incoming webhook, or for
+// Matrix either a hookshot webhook or, together with Room, the homeserver.
+type Push struct {
+	Name    string   `yaml:"name"`
+	Service string   `yaml:"service"`
+	URL     string   `yaml:"url"`
+	Token   string   `yaml:"token"`
+	Room    string   `yaml:"room"`
+	Filter  string   `yaml:"filter"`
+	Events  []string `yaml:"events"`
+	Retries *int     `yaml:"retries"`
+	Timeout string   `yaml:"timeout"`
+}
+
+func validateNotifications(c *Config) error {
+	names := make(map[string]bool)
+	for i := range c.Notifications.Webhooks {
+		webhook := &c.Notifications.Webhooks[i]
+		webhook.Name = strings.TrimSpace(webhook.Name)
+		if webhook.Name == "" {
+			return fmt.Errorf("webhook %d has no name", i+1)
+		}
+		if names[webhook.Name] {
+			return fmt.Errorf("notification %q is defined twice", webhook.Name)
+		}
+		names[webhook.Name] = true
+
+		if webhook.URL == "" {
+			return fmt.Errorf("webhook %q has no url", webhook.Name)
+		}
+		if webhook.Method == "" {
+			webhook.Method = http.MethodPost
+		}
+		webhook.Method = strings.ToUpper(webhook.Method)
+		if err := validateFilter(webhook.Name, &webhook.Filter); err != nil {
+			return err
+		}
+		if webhook.Body != "" {
+			if _, err := template.New(webhook.Name).Funcs(util.TemplateFuncs).Parse(webhook.Body); err != nil {
+				return fmt.Errorf("invalid body template of webhook %q: %w", webhook.Name, err)
+			}
+		}
+		if webhook.Retries != nil && *webhook.Retries < 0 {
+			return fmt.Errorf("retries of webhook %q must not be negative", webhook.Name)
+		}
+		if webhook.Timeout != "" {
+			if _, err := time.ParseDuration(webhook.Timeout); err != nil {
+				return fmt.Errorf("invalid timeout of webhook %q: %w", webhook.Name, err)
+			}
+		}
+	}
+
+	for i := range c.Notifications.Email {
+		email := &c.Notifications.Email[i]
+		email.Name = strings.TrimSpace(email.Name)
+		if email.Name == "" {
+			return fmt.Errorf("email %d has no name", i+1)
+		}
+		if names[email.Name] {
+			return fmt.Errorf("notification %q is defined twice", email.Name)
+		}
+		names[email.Name] = true
+
+		if err := validateEmail(email); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Notifications.Push {
+		push := &c.Notifications.Push[i]
+		push.Name = strings.TrimSpace(push.Name)
+		if push.Name == "" {
+			return fmt.Errorf("push notification %d has no name", i+1)
+		}
+		if names[push.Name] {
+			return fmt.Errorf("notification %q is defined twice", push.Name)
+		}
+		names[push.Name] = true
+
+		if err := validatePush(push); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePush(push *Push) error {
+	push.Service = strings.ToLower(push.Service)
+	switch push.Service {
+	case PushNtfy, PushSlack:
+	case PushGotify:
+		if push.Token == "" {
+			return fmt.Errorf("push notification %q needs the token of a gotify application", push.Name)
+		}
+	case PushMatrix:
+		if push.Room != "" && push.Token == "" {
+			return fmt.Errorf("push notification %q needs an access token to post to room %s", push.Name, push.Room)
+		}
+	default:
+		return fmt.Errorf("service of push notification %q must be %q, %q, %q or %q", push.Name, PushNtfy, PushGotify, PushSlack, PushMatrix)
+	}
+
+	if push.URL == "" {
+		return fmt.Errorf("push notification %q has no url", push.Name)
+	}
+	if push.Service == PushNtfy && strings.Trim(path.Base(push.URL), "/") == "" {
+		return fmt.Errorf("url of push notification %q must include the ntfy topic", push.Name)
+	}
+	if err := validateFilter(push.Name, &push.Filter); err != nil {
+		return err
+	}
+	if push.Retries != nil && *push.Retries < 0 {
+		return fmt.Errorf("retries of push notification %q must not be negative", push.Name)
+	}
+	if push.Timeout != "" {
+		if _, err := time.ParseDuration(push.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout of push notification %q: %w", push.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateEmail(email *Email) error {
+	if email.Host == "" {
+		return fmt.Errorf("email %q has no host", email.Name)
+	}
+
+	email.TLS = strings.ToLower(email.TLS)
+	switch email.TLS {
+	case "":
+		email.TLS = EmailTLSStartTLS
+	case EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone:
+	default:
+		return fmt.Errorf("tls of email %q must be %q, %q or %q", email.Name, EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone)
+	}
+	if email.Port == 0 {
+		switch email.TLS {
+		case EmailTLSImplicit:
+			email.Port = 465
+		case EmailTLSNone:
+			email.Port = 25
+		default:
+			email.Port = 587
+		}
+	}
+	if email.Port < 0 || email.Port > 65535 {
+		return fmt.Errorf("invalid port of email %q", email.Name)
+	}
+
+	if _, err := mail.ParseAddress(email.From); err != nil {
+		return fmt.Errorf("invalid from address of email %q: %w", email.Name, err)
+	}
+	if len(email.To) == 0 {
+		return fmt.Errorf("email %q has no recipients", email.Name)
+	}
+	for _, to := range email.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid recipient %q of email %q: %w", to, email.Name, err)
+		}
+	}
+
+	if err := validateFilter(email.Name, &email.Filter); err != nil {
+		return err
+	}
+	switch email.Digest {
+	case "", DigestDaily, DigestWeekly:
+	default:
+		return fmt.Errorf("digest of email %q must be %q or %q", email.Name, DigestDaily, DigestWeekly)
+	}
+	if email.DigestTime == "" {
+		email.DigestTime = DefaultDigestTime
+	}
+	if _, err := time.Parse("15:04", email.DigestTime); err != nil {
+		return fmt.Errorf("invalid digest_time of email %q, expected HH:MM", email.Name)
+	}
+	if email.Retries != nil && *email.Retries < 0 {
+		return fmt.Errorf("retries of email %q must not be negative", email.Name)
+	}
+	if email.Timeout != "" {
+		if _, err := time.ParseDuration(email.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout of email %q: %w", email.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateFilter(name string, filter *string) error {
+	switch *filter {
+	case "":
+		*filter = NotifyOnFailure
+	case NotifyOnFailure, NotifyAlways:
+	default:
+		return fmt.Errorf("filter of notification %q must be %q or %q", name, NotifyOnFailure, NotifyAlways)
+	}
+
+	return nil
+}
+
+// RetryCount returns how often a failed delivery is repeated.
+func (w Webhook) RetryCount() int {
+	if w.Retries == nil {
+		return DefaultWebhookRetries
+	}
+
+	return *w.Retries
+}
+
+func (w Webhook) RequestTimeout() time.Duration {
+	timeout, err := time.ParseDuration(w.Timeout)
+	if w.Timeout == "" || err != nil {
+		return 10 * time.Second
+	}
+
+	return timeout
+}
+
+func (p Push) RetryCount() int {
+	if p.Retries == nil {
+		return DefaultWebhookRetries
+	}
+
+	return *p.Retries
+}
+
+func (p Push) RequestTimeout() time.Duration {
+	timeout, err := time.ParseDuration(p.Timeout)
+	if p.Timeout == "" || err != nil {
+		return 10 * time.Second
+	}
+
+	return timeout
+}
+
+func (e Email) RetryCount() int {
+	if e.Retries == nil {
+		return DefaultWebhookRetries
+	}
+
+	return *e.Retries
+}
+
+func (e Email) RequestTimeout() time.Duration {
+	timeout, err := time.ParseDuration(e.Timeout)
+	if e.Timeout == "" || err != nil {
+		return 30 * time.Second
+	}
+
+	return timeout
+}
+
+// DigestCron returns the cron expression the digest is sent at, or an empty
+// string when a report is sent after every run.
+func (e Email) DigestCron() string {
+	at, err := time.Parse("15:04", e.DigestTime)
+	if err != nil {
+		at, _ = time.Parse("15:04", DefaultDigestTime)
+	}
+
+	switch e.Digest {
+	case DigestDaily:
+		return fmt.Sprintf("%d %d * * *", at.Minute(), at.Hour())
+	case DigestWeekly:
+		return fmt.Sprintf("%d %d * * 1", at.Minute(), at.Hour())
+	}
+
+	return ""
+}
+
+// DigestPeriod is the time span one digest covers.
+func (e Email) DigestPeriod() time.Duration {
+	if e.Digest == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+
+	return 24 * time.Hour
+}
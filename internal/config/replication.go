@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"prostic/internal/util"
+)
+
+func validateReplications(c *Config) error {
+	repositories := make(map[string]bool, len(c.Repositories))
+	for _, repository := range c.Repositories {
+		repositories[repository.Name] = true
+	}
+	defaultSource := c.Backup.Repository
+	if defaultSource == "" && len(c.Repositories) > 0 {
+		defaultSource = c.Repositories[0].Name
+	}
+
+	names := make(map[string]bool, len(c.Replications))
+	for i := range c.Replications {
+		replication := &c.Replications[i]
+		replication.Name = strings.TrimSpace(replication.Name)
+		if replication.Name == "" {
+			return fmt.Errorf("replication %d has no name", i+1)
+		}
+		if names[replication.Name] {
+			return fmt.Errorf("replication %q is defined twice", replication.Name)
+		}
+		names[replication.Name] = true
+
+		if replication.Source == "" {
+			replication.Source = defaultSource
+		}
+		if !repositories[replication.Source] {
+			return fmt.Errorf("source repository %q of replication %q is not defined", replication.Source, replication.Name)
+		}
+		if len(replication.Targets) == 0 {
+			return fmt.Errorf("replication %q has no targets", replication.Name)
+		}
+		for _, target := range replication.Targets {
+			if !repositories[target] {
+				return fmt.Errorf("target repository %q of replication %q is not defined", target, replication.Name)
+			}
+			if target == replication.Source {
+				return fmt.Errorf("replication %q copies %q onto itself", replication.Name, target)
+			}
+		}
+
+		if replication.Cron != "" {
+			if _, err := util.CronMatches(replication.Cron, time.Now()); err != nil {
+				return fmt.Errorf("invalid cron expression of replication %q", replication.Name)
+			}
+		}
+		if replication.MaxAge != "" {
+			if _, err := time.ParseDuration(replication.MaxAge); err != nil {
+				return fmt.Errorf("invalid max_age of replication %q", replication.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func FindReplication(name string) *Replication {
+	if cfg == nil {
+		return nil
+	}
+
+	for i := range cfg.Replications {
+		if cfg.Replications[i].Name == name {
+			return &cfg.Replications[i]
+		}
+	}
+
+	return nil
+}
+
+// MaxAgeDuration returns how far back backups are replicated; zero means no
+// limit.
+func (r Replication) MaxAgeDuration() time.Duration {
+	if r.MaxAge == "" {
+		return 0
+	}
+
+	duration, _ := time.ParseDuration(r.MaxAge)
+	return duration
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const DefaultRepositoryName = "default"
+
+func normalizeRepositories(c *Config) error {
+	if len(c.Repositories) == 0 && len(c.Restic.EnvVars) > 0 {
+		c.Repositories = []Repository{{
+			Name: DefaultRepositoryName,
+			Env:  c.Restic.EnvVars,
+		}}
+	}
+
+	seen := make(map[string]bool, len(c.Repositories))
+	for i := range c.Repositories {
+		name := strings.TrimSpace(c.Repositories[i].Name)
+		if name == "" {
+			return fmt.Errorf("repository %d has no name", i+1)
+		}
+		if seen[name] {
+			return fmt.Errorf("repository %q is defined twice", name)
+		}
+		seen[name] = true
+		c.Repositories[i].Name = name
+	}
+
+	if c.Backup.Repository != "" && !seen[c.Backup.Repository] {
+		return fmt.Errorf("backup repository %q is not defined", c.Backup.Repository)
+	}
+	for _, vm := range c.VMs {
+		if vm.Repository != "" && !seen[vm.Repository] {
+			return fmt.Errorf("repository %q of vm %d is not defined", vm.Repository, vm.ID)
+		}
+	}
+
+	return nil
+}
+
+func FindRepository(name string) *Repository {
+	if cfg == nil {
+		return nil
+	}
+	if name == "" {
+		name = DefaultRepository()
+	}
+
+	for i := range cfg.Repositories {
+		if cfg.Repositories[i].Name == name {
+			return &cfg.Repositories[i]
+		}
+	}
+
+	return nil
+}
+
+// DefaultRepository returns the repository used when neither the job nor the
+// guest selects one.
+func DefaultRepository() string {
+	if cfg == nil {
+		return ""
+	}
+	if cfg.Backup.Repository != "" {
+		return cfg.Backup.Repository
+	}
+	if len(cfg.Repositories) > 0 {
+		return cfg.Repositories[0].Name
+	}
+
+	return ""
+}
+
+func RepositoryNames() []string {
+	if cfg == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Repositories))
+	for _, repository := range cfg.Repositories {
+		names = append(names, repository.Name)
+	}
+
+	return names
+}
+
+// TargetRepository resolves where a guest is backed up to. A guest setting
+// wins over the job target, which wins over the default repository.
+func TargetRepository(vm VM, jobRepository string) string {
+	if vm.Repository != "" {
+		return vm.Repository
+	}
+	if jobRepository != "" {
+		return jobRepository
+	}
+
+	return DefaultRepository()
+}
+
+// Kind derives the backend type from RESTIC_REPOSITORY.
+func (r Repository) Kind() string {
+	location := r.Env["RESTIC_REPOSITORY"]
+	if location == "" && r.Env["RESTIC_REPOSITORY_FILE"] != "" {
+		return "unknown"
+	}
+
+	scheme, _, found := strings.Cut(location, ":")
+	if !found || strings.HasPrefix(location, "/") {
+		return "local"
+	}
+
+	switch scheme {
+	case "local", "sftp", "s3", "rest", "b2", "azure", "gs", "swift", "rclone":
+		return scheme
+	}
+
+	return "local"
+}
+
+// Validate checks that the environment tells restic where the repository is
+// and how to unlock it.
+func (r Repository) Validate() error {
+	if r.Env["RESTIC_REPOSITORY"] == "" && r.Env["RESTIC_REPOSITORY_FILE"] == "" {
+		return fmt.Errorf("repository %q: RESTIC_REPOSITORY or RESTIC_REPOSITORY_FILE is required", r.Name)
+	}
+	if r.Env["RESTIC_PASSWORD"] == "" && r.Env["RESTIC_PASSWORD_FILE"] == "" && r.Env["RESTIC_PASSWORD_COMMAND"] == "" {
+		return fmt.Errorf("repository %q: RESTIC_PASSWORD, RESTIC_PASSWORD_FILE or RESTIC_PASSWORD_COMMAND is required", r.Name)
+	}
+
+	for _, key := range []string{"RESTIC_REPOSITORY_FILE", "RESTIC_PASSWORD_FILE"} {
+		if path := r.Env[key]; path != "" {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("repository %q: %s: %w", r.Name, key, err)
+			}
+		}
+	}
+
+	return nil
+}
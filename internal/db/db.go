@@ -41,12 +41,32 @@ func Get() (*gorm.DB, error) {
 			return
 		}
 
-		initErr = instance.AutoMigrate(&models.Setting{}, &models.Snapshot{}, &models.RepoStat{}, &models.Task{}, &models.BackupRun{})
+		initErr = dropLegacyIndexes()
+		if initErr != nil {
+			return
+		}
+
+		initErr = instance.AutoMigrate(&models.Setting{}, &models.Snapshot{}, &models.RepoStat{}, &models.Task{}, &models.BackupRun{}, &models.BackupItem{}, &models.Replication{}, &models.RepoCheck{}, &models.SnapshotStat{}, &models.NotificationDelivery{}, &models.LogLine{}, &models.User{}, &models.SLAState{})
+		if initErr != nil {
+			return
+		}
+
+		initErr = assignLegacyRepository()
+		if initErr != nil {
+			return
+		}
+
+		initErr = backfillFirstSeen()
 		if initErr != nil {
 			return
 		}
 
 		initErr = ensureDefaultSettings()
+		if initErr != nil {
+			return
+		}
+
+		initErr = ensureAdminUser()
 	})
 
 	return instance, initErr
@@ -66,6 +86,40 @@ func defaultDBPath() string {
 	return filepath.Join("data", configName+".db")
 }
 
+// dropLegacyIndexes removes indexes that AutoMigrate would otherwise keep
+// after their columns were regrouped.
+func dropLegacyIndexes() error {
+	migrator := instance.Migrator()
+	if migrator.HasTable(&models.Snapshot{}) && migrator.HasIndex(&models.Snapshot{}, "idx_snapshots_snapshot_id") {
+		return migrator.DropIndex(&models.Snapshot{}, "idx_snapshots_snapshot_id")
+	}
+
+	return nil
+}
+
+// assignLegacyRepository moves rows cached before repositories were named to
+// the default repository.
+func assignLegacyRepository() error {
+	repository := config.DefaultRepository()
+	if repository == "" {
+		return nil
+	}
+
+	for _, model := range []interface{}{&models.Snapshot{}, &models.RepoStat{}, &models.BackupItem{}} {
+		if err := instance.Model(model).Where("repository = ''").Update("repository", repository).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backfillFirstSeen uses the cache time for snapshots cached before first
+// seen times were recorded.
+func backfillFirstSeen() error {
+	return instance.Model(&models.Snapshot{}).Where("first_seen_at IS NULL").Update("first_seen_at", gorm.Expr("created_at")).Error
+}
+
 func ensureDefaultSettings() error {
 	var settings models.Setting
 	err := instance.First(&settings, 1).Error
@@ -90,3 +144,27 @@ func ensureDefaultSettings() error {
 
 	return instance.Create(&settings).Error
 }
+
+// ensureAdminUser creates the admin user with the password that was shared
+// by everyone before user accounts, so existing logins keep working.
+func ensureAdminUser() error {
+	var count int64
+	if err := instance.Model(&models.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var settings models.Setting
+	if err := instance.First(&settings, 1).Error; err != nil {
+		return err
+	}
+
+	return instance.Create(&models.User{
+		Username:            "admin",
+		PasswordHash:        settings.PasswordHash,
+		Role:                "admin",
+		NeedsPasswordChange: settings.NeedsPasswordChange,
+	}).Error
+}
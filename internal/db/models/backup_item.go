@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type BackupItem struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	BackupRunID         uint      `gorm:"index;not null" json:"backupRunID"`
+	BackupID            string    `gorm:"index" json:"backupID"`
+	Repository          string    `gorm:"index;not null;default:''" json:"repository"`
+	VMID                int       `gorm:"index;not null" json:"vmid"`
+	VMName              string    `json:"vmName"`
+	ItemType            string    `gorm:"index;not null" json:"itemType"`
+	SrcFile             string    `gorm:"type:text" json:"srcFile"`
+	DestFile            string    `gorm:"type:text" json:"destFile"`
+	SnapshotID          string    `gorm:"index" json:"snapshotID"`
+	DataAdded           int64     `json:"dataAdded"`
+	DataAddedPacked     int64     `json:"dataAddedPacked"`
+	TotalBytesProcessed int64     `json:"totalBytesProcessed"`
+	DurationSeconds     float64   `json:"durationSeconds"`
+	Throughput          float64   `json:"throughput"`
+	DedupRatio          float64   `json:"dedupRatio"`
+	StartedAt           time.Time `gorm:"index;not null" json:"startedAt"`
+	FinishedAt          time.Time `json:"finishedAt"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
@@ -3,15 +3,22 @@ package models
 import "time"
 
 type BackupRun struct {
-	ID             uint       `gorm:"primaryKey" json:"id"`
-	BackupID       string     `gorm:"index" json:"backupID"`
-	Trigger        string     `gorm:"index;not null" json:"trigger"`
-	Status         string     `gorm:"index;not null" json:"status"`
-	Logs           string     `gorm:"type:text" json:"logs"`
-	TotalItems     int        `gorm:"not null;default:0" json:"totalItems"`
-	CompletedItems int        `gorm:"not null;default:0" json:"completedItems"`
-	StartedAt      time.Time  `gorm:"index;not null" json:"startedAt"`
-	FinishedAt     *time.Time `json:"finishedAt"`
-	CreatedAt      time.Time  `json:"createdAt"`
-	UpdatedAt      time.Time  `json:"updatedAt"`
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	BackupID       string `gorm:"index" json:"backupID"`
+	Trigger        string `gorm:"index;not null" json:"trigger"`
+	Repository     string `gorm:"not null;default:''" json:"repository"`
+	Status         string `gorm:"index;not null" json:"status"`
+	Logs           string `gorm:"type:text" json:"logs"`
+	TotalItems     int    `gorm:"not null;default:0" json:"totalItems"`
+	CompletedItems int    `gorm:"not null;default:0" json:"completedItems"`
+	// PlannedItems holds the items the run set out to back up as JSON.
+	PlannedItems        string     `gorm:"type:text" json:"-"`
+	DataAdded           int64      `gorm:"not null;default:0" json:"dataAdded"`
+	DataAddedPacked     int64      `gorm:"not null;default:0" json:"dataAddedPacked"`
+	TotalBytesProcessed int64      `gorm:"not null;default:0" json:"totalBytesProcessed"`
+	TotalFilesProcessed int64      `gorm:"not null;default:0" json:"totalFilesProcessed"`
+	StartedAt           time.Time  `gorm:"index;not null" json:"startedAt"`
+	FinishedAt          *time.Time `json:"finishedAt"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LogLine is one line of the output of a running task or backup run. Lines
+// are stored as they are written so the output survives a crash.
+type LogLine struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Source    string    `gorm:"index:idx_log_lines_source,priority:1;not null" json:"source"`
+	SourceID  uint      `gorm:"index:idx_log_lines_source,priority:2;not null" json:"sourceID"`
+	Seq       int       `gorm:"index:idx_log_lines_source,priority:3;not null" json:"seq"`
+	Line      string    `gorm:"type:text" json:"line"`
+	CreatedAt time.Time `json:"createdAt"`
+}
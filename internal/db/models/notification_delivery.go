@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type NotificationDelivery struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Channel     string     `gorm:"index;not null" json:"channel"`
+	Kind        string     `gorm:"not null" json:"kind"`
+	EventType   string     `gorm:"index;not null" json:"eventType"`
+	EventStatus string     `json:"eventStatus"`
+	Title       string     `json:"title"`
+	Status      string     `gorm:"index;not null" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	StatusCode  int        `json:"statusCode"`
+	Error       string     `gorm:"type:text" json:"error"`
+	StartedAt   time.Time  `gorm:"index;not null" json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
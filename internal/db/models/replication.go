@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Replication struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Name          string    `gorm:"index;not null" json:"name"`
+	BackupID      string    `gorm:"index;not null" json:"backupID"`
+	Source        string    `gorm:"index;not null" json:"source"`
+	Target        string    `gorm:"index;not null" json:"target"`
+	Status        string    `gorm:"index;not null" json:"status"`
+	SnapshotCount int       `gorm:"not null;default:0" json:"snapshotCount"`
+	TaskID        uint      `gorm:"index" json:"taskID"`
+	BackupTime    time.Time `json:"backupTime"`
+	ReplicatedAt  time.Time `gorm:"index;not null" json:"replicatedAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
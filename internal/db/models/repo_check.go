@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type RepoCheck struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	Repository      string     `gorm:"index;not null" json:"repository"`
+	Status          string     `gorm:"index;not null" json:"status"`
+	ReadDataSubset  string     `json:"readDataSubset"`
+	SubsetIndex     int        `gorm:"not null;default:0" json:"subsetIndex"`
+	SubsetCount     int        `gorm:"not null;default:0" json:"subsetCount"`
+	Output          string     `gorm:"type:text" json:"output"`
+	ErrorOutput     string     `gorm:"type:text" json:"errorOutput"`
+	TaskID          uint       `gorm:"index" json:"taskID"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	StartedAt       time.Time  `gorm:"index;not null" json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
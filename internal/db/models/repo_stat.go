@@ -4,6 +4,7 @@ import "time"
 
 type RepoStat struct {
 	ID                     uint      `gorm:"primaryKey" json:"id"`
+	Repository             string    `gorm:"index;not null;default:''" json:"repository"`
 	TotalSize              int64     `json:"totalSize"`
 	TotalUncompressedSize  int64     `json:"totalUncompressedSize"`
 	CompressionRatio       float64   `json:"compressionRatio"`
@@ -3,7 +3,9 @@ package models
 import "time"
 
 type Setting struct {
-	ID                  uint   `gorm:"primaryKey"`
+	ID uint `gorm:"primaryKey"`
+	// PasswordHash is the password from before user accounts. It seeds the
+	// admin user and is not used for logins.
 	PasswordHash        string `gorm:"not null"`
 	NeedsPasswordChange bool   `gorm:"not null;default:false"`
 	BackupCron          string `gorm:"not null;default:''"`
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SLAState is the last known SLA state of a guest, kept so that a restart
+// does not report every breach again.
+type SLAState struct {
+	VMID      int       `gorm:"primaryKey;autoIncrement:false" json:"vmid"`
+	Breached  bool      `gorm:"not null" json:"breached"`
+	Since     time.Time `gorm:"not null" json:"since"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -4,20 +4,25 @@ import "time"
 
 type Snapshot struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
-	SnapshotID   string    `gorm:"uniqueIndex;not null" json:"snapshotID"`
-	Time         time.Time `gorm:"index" json:"time"`
+	Repository   string    `gorm:"uniqueIndex:idx_snapshots_repository_snapshot_id,priority:1;index:idx_snapshots_repository_time,priority:1;index:idx_snapshots_repository_vm_id_time,priority:1;not null;default:''" json:"repository"`
+	SnapshotID   string    `gorm:"uniqueIndex:idx_snapshots_repository_snapshot_id,priority:2;not null" json:"snapshotID"`
+	Time         time.Time `gorm:"index;index:idx_snapshots_repository_time,priority:2;index:idx_snapshots_repository_vm_id_time,priority:3" json:"time"`
 	Hostname     string    `json:"hostname"`
 	Tree         string    `json:"tree"`
+	Parent       string    `json:"parent"`
 	Paths        string    `gorm:"type:text" json:"paths"`
 	Tags         string    `gorm:"type:text" json:"tags"`
 	BackupID     string    `gorm:"index" json:"backupID"`
-	VMID         *int      `gorm:"index" json:"vmid"`
+	VMID         *int      `gorm:"index;index:idx_snapshots_repository_vm_id_time,priority:2" json:"vmid"`
 	Name         string    `json:"name"`
 	VMType       string    `json:"vmType"`
 	SnapshotType string    `gorm:"index" json:"snapshotType"`
 	BackupDate   string    `json:"backupDate"`
 	DestFile     string    `gorm:"type:text" json:"destFile"`
 	SrcFile      string    `gorm:"type:text" json:"srcFile"`
+	Size         int64     `gorm:"not null;default:0" json:"size"`
+	DataAdded    int64     `gorm:"not null;default:0" json:"dataAdded"`
+	FirstSeenAt  time.Time `gorm:"index" json:"firstSeenAt"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
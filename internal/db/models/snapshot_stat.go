@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SnapshotStat caches `restic stats` of a single snapshot. Snapshots never
+// change, so entries never need to be recomputed.
+type SnapshotStat struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	Repository          string    `gorm:"uniqueIndex:idx_snapshot_stats_repository_snapshot_id,priority:1;not null" json:"repository"`
+	SnapshotID          string    `gorm:"uniqueIndex:idx_snapshot_stats_repository_snapshot_id,priority:2;not null" json:"snapshotID"`
+	RestoreSize         int64     `gorm:"not null;default:0" json:"restoreSize"`
+	RestoreFileCount    int64     `gorm:"not null;default:0" json:"restoreFileCount"`
+	RawDataSize         int64     `gorm:"not null;default:0" json:"rawDataSize"`
+	RawUncompressedSize int64     `gorm:"not null;default:0" json:"rawUncompressedSize"`
+	RawBlobCount        int64     `gorm:"not null;default:0" json:"rawBlobCount"`
+	RawCompressionRatio float64   `gorm:"not null;default:0" json:"rawCompressionRatio"`
+	ComputedAt          time.Time `gorm:"not null" json:"computedAt"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type User struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	Username            string    `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash        string    `gorm:"not null" json:"-"`
+	Role                string    `gorm:"not null" json:"role"`
+	NeedsPasswordChange bool      `gorm:"not null;default:false" json:"needsPasswordChange"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+func CreateBackupItem(item *models.BackupItem) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Create(item).Error
+}
+
+func ListBackupItemsForVM(vmID int, limit int) ([]models.BackupItem, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Where("vm_id = ?", vmID).Order("started_at desc, id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var items []models.BackupItem
+	if err := query.Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func ListBackupItemsForRun(runID uint) ([]models.BackupItem, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.BackupItem
+	if err := database.Where("backup_run_id = ?", runID).Order("started_at asc, id asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func ListBackupItemsForBackupID(backupID string) ([]models.BackupItem, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.BackupItem
+	if err := database.Where("backup_id = ?", backupID).Order("started_at asc, id asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"errors"
 	"time"
 
+	"gorm.io/gorm"
+
 	"prostic/internal/db"
 	"prostic/internal/db/models"
 )
@@ -36,6 +39,45 @@ func FinishBackupRun(runID uint, status string, logs string, backupID string, co
 	})
 }
 
+// FindBackupRunByBackupID returns the run that created backupID. Guests of
+// one run may be written to different repositories, so the repository is not
+// part of the lookup.
+func FindBackupRunByBackupID(backupID string) (*models.BackupRun, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var run models.BackupRun
+	if err := database.Where("backup_id = ?", backupID).Order("started_at desc").First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+func GetBackupRun(runID uint) (*models.BackupRun, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var run models.BackupRun
+	if err := database.First(&run, runID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &run, nil
+}
+
 func ListBackupRuns(limit int) ([]models.BackupRun, error) {
 	database, err := db.Get()
 	if err != nil {
@@ -54,3 +96,84 @@ func ListBackupRuns(limit int) ([]models.BackupRun, error) {
 
 	return runs, nil
 }
+
+// ListBackupRunsByBackupID returns the runs of backupIDs keyed by backup ID,
+// without their logs.
+func ListBackupRunsByBackupID(backupIDs []string) (map[string]models.BackupRun, error) {
+	runs := make(map[string]models.BackupRun, len(backupIDs))
+	if len(backupIDs) == 0 {
+		return runs, nil
+	}
+
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []models.BackupRun
+	if err := database.Omit("logs").Where("backup_id IN ?", backupIDs).Order("started_at asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, run := range rows {
+		runs[run.BackupID] = run
+	}
+
+	return runs, nil
+}
+
+// ListBackupRunsSince returns the runs started after since, oldest first and
+// without their logs.
+func ListBackupRunsSince(since time.Time) ([]models.BackupRun, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []models.BackupRun
+	if err := database.Omit("logs").Where("started_at > ?", since).Order("started_at asc, id asc").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// GetLatestFinishedBackupRun returns the newest run of repository that is no
+// longer running, without its logs. An empty status matches any outcome.
+func GetLatestFinishedBackupRun(repository string, status string) (*models.BackupRun, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Omit("logs").Where("repository = ? AND finished_at IS NOT NULL", repository)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var run models.BackupRun
+	if err := query.Order("started_at desc, id desc").First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// ListUnfinishedBackupRuns returns the runs that never got a finish time.
+func ListUnfinishedBackupRuns() ([]models.BackupRun, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []models.BackupRun
+	if err := database.Omit("logs").Where("finished_at IS NULL").Order("started_at asc, id asc").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
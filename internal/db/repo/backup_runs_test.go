@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"prostic/internal/db/models"
+)
+
+func TestGetLatestFinishedBackupRun(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	finished := func(offset time.Duration) *time.Time {
+		value := base.Add(offset + time.Minute)
+		return &value
+	}
+	runs := []models.BackupRun{
+		{Trigger: "schedule", Repository: "primary", Status: "success", StartedAt: base, FinishedAt: finished(0)},
+		{Trigger: "manual", Repository: "primary", Status: "failed", StartedAt: base.Add(time.Hour), FinishedAt: finished(time.Hour)},
+		{Trigger: "manual", Repository: "primary", Status: "running", StartedAt: base.Add(2 * time.Hour)},
+		{Trigger: "schedule", Repository: "offsite", Status: "success", StartedAt: base.Add(3 * time.Hour), FinishedAt: finished(3 * time.Hour)},
+	}
+	for i := range runs {
+		if err := CreateBackupRun(&runs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		repository string
+		status     string
+		want       uint
+	}{
+		{name: "any status", repository: "primary", want: runs[1].ID},
+		{name: "last success", repository: "primary", status: "success", want: runs[0].ID},
+		{name: "other repository", repository: "offsite", want: runs[3].ID},
+		{name: "no match", repository: "offsite", status: "failed"},
+		{name: "unknown repository", repository: "missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run, err := GetLatestFinishedBackupRun(test.repository, test.status)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got uint
+			if run != nil {
+				got = run.ID
+			}
+			if got != test.want {
+				t.Errorf("run = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
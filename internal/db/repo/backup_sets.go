@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+type BackupSetSummary struct {
+	BackupID      string    `json:"backupID"`
+	Repositories  []string  `json:"repositories"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+	SnapshotCount int       `json:"snapshotCount"`
+	GuestCount    int       `json:"guestCount"`
+	ItemCount     int       `json:"itemCount"`
+	TotalSize     int64     `json:"totalSize"`
+	DataAdded     int64     `json:"dataAdded"`
+}
+
+// ListBackupSets aggregates cached snapshots by backup ID, newest first. An
+// item copied to several repositories counts once. An empty repository
+// covers all repositories; limit zero returns every backup.
+func ListBackupSets(repository string, limit int) ([]BackupSetSummary, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := backupSetQuery(database, repository).Order("start_time desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	return scanBackupSets(query)
+}
+
+func GetBackupSet(repository string, backupID string) (*BackupSetSummary, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := scanBackupSets(backupSetQuery(database, repository).Having("backup_id = ?", backupID))
+	if err != nil || len(sets) == 0 {
+		return nil, err
+	}
+
+	return &sets[0], nil
+}
+
+func ListBackupSetSnapshots(repository string, backupID string) ([]models.Snapshot, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Where("backup_id = ?", backupID)
+	if repository != "" {
+		query = query.Where("repository = ?", repository)
+	}
+
+	var snapshots []models.Snapshot
+	if err := query.Order("vm_id asc, snapshot_type asc, src_file asc, repository asc").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// ListBackupSetItems returns the snapshots of several backup IDs by backup ID,
+// with only the columns that identify the backed up item.
+func ListBackupSetItems(repository string, backupIDs []string) (map[string][]models.Snapshot, error) {
+	items := make(map[string][]models.Snapshot, len(backupIDs))
+	if len(backupIDs) == 0 {
+		return items, nil
+	}
+
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Select("backup_id", "vm_id", "snapshot_type", "src_file", "repository").Where("backup_id IN ?", backupIDs)
+	if repository != "" {
+		query = query.Where("repository = ?", repository)
+	}
+
+	var snapshots []models.Snapshot
+	if err := query.Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		items[snapshot.BackupID] = append(items[snapshot.BackupID], snapshot)
+	}
+
+	return items, nil
+}
+
+func backupSetQuery(database *gorm.DB, repository string) *gorm.DB {
+	items := database.Model(&models.Snapshot{}).
+		Select("backup_id, vm_id, MIN(time) AS start_time, MAX(time) AS end_time, MAX(size) AS size, MAX(data_added) AS data_added, COUNT(*) AS copies, GROUP_CONCAT(DISTINCT repository) AS repositories").
+		Where("backup_id <> ''").
+		Group("backup_id, vm_id, snapshot_type, src_file")
+	if repository != "" {
+		items = items.Where("repository = ?", repository)
+	}
+
+	return database.Table("(?) AS items", items).
+		Select("backup_id, MIN(start_time) AS start_time, MAX(end_time) AS end_time, SUM(copies) AS snapshot_count, COUNT(DISTINCT vm_id) AS guest_count, COUNT(*) AS item_count, SUM(size) AS total_size, SUM(data_added) AS data_added, GROUP_CONCAT(repositories) AS repositories").
+		Group("backup_id")
+}
+
+func scanBackupSets(query *gorm.DB) ([]BackupSetSummary, error) {
+	var rows []struct {
+		BackupID      string
+		StartTime     string
+		EndTime       string
+		SnapshotCount int
+		GuestCount    int
+		ItemCount     int
+		TotalSize     int64
+		DataAdded     int64
+		Repositories  string
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	sets := make([]BackupSetSummary, 0, len(rows))
+	for _, row := range rows {
+		sets = append(sets, BackupSetSummary{
+			BackupID:      row.BackupID,
+			Repositories:  splitRepositories(row.Repositories),
+			StartTime:     parseSQLiteTime(row.StartTime),
+			EndTime:       parseSQLiteTime(row.EndTime),
+			SnapshotCount: row.SnapshotCount,
+			GuestCount:    row.GuestCount,
+			ItemCount:     row.ItemCount,
+			TotalSize:     row.TotalSize,
+			DataAdded:     row.DataAdded,
+		})
+	}
+
+	return sets, nil
+}
+
+// splitRepositories dedupes the nested GROUP_CONCAT of both query levels.
+func splitRepositories(value string) []string {
+	seen := make(map[string]bool)
+	repositories := make([]string, 0)
+	for _, repository := range strings.Split(value, ",") {
+		if repository == "" || seen[repository] {
+			continue
+		}
+		seen[repository] = true
+		repositories = append(repositories, repository)
+	}
+	sort.Strings(repositories)
+
+	return repositories
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+const (
+	LogSourceTask      = "task"
+	LogSourceBackupRun = "backup_run"
+)
+
+func CreateLogLines(lines []models.LogLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.CreateInBatches(lines, 100).Error
+}
+
+// ListLogLines returns the stored lines of one task or run in order.
+func ListLogLines(source string, sourceID uint) ([]models.LogLine, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []models.LogLine
+	if err := database.Where("source = ? AND source_id = ?", source, sourceID).Order("seq asc").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// DeleteLogLines removes the lines of one task or run up to and including
+// seq. A seq of zero or less removes all lines.
+func DeleteLogLines(source string, sourceID uint, seq int) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	query := database.Where("source = ? AND source_id = ?", source, sourceID)
+	if seq > 0 {
+		query = query.Where("seq <= ?", seq)
+	}
+
+	return query.Delete(&models.LogLine{}).Error
+}
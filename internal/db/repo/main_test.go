@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "prostic-repo-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("PROSTIC_DB_PATH", filepath.Join(dir, "test.db"))
+	// a zone away from UTC so comparisons that ignore offsets fail
+	time.Local = time.FixedZone("test", 2*60*60)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
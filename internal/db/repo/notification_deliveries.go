@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+func CreateNotificationDelivery(delivery *models.NotificationDelivery) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Create(delivery).Error
+}
+
+func UpdateNotificationDelivery(delivery *models.NotificationDelivery) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Save(delivery).Error
+}
+
+func ListNotificationDeliveries(channel string, limit int) ([]models.NotificationDelivery, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Order("started_at desc, id desc")
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
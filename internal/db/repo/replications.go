@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+const ReplicationStatusSuccess = "success"
+
+func CreateReplication(replication *models.Replication) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Create(replication).Error
+}
+
+// ListReplicatedBackupIDs returns the backup IDs that were copied from source
+// to target successfully at least once.
+func ListReplicatedBackupIDs(source string, target string) (map[string]bool, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var backupIDs []string
+	if err := database.Model(&models.Replication{}).
+		Where("source = ? AND target = ? AND status = ?", source, target, ReplicationStatusSuccess).
+		Distinct().
+		Pluck("backup_id", &backupIDs).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(backupIDs))
+	for _, backupID := range backupIDs {
+		out[backupID] = true
+	}
+
+	return out, nil
+}
+
+func GetLastReplication(source string, target string) (*models.Replication, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var replication models.Replication
+	err = database.Where("source = ? AND target = ? AND status = ?", source, target, ReplicationStatusSuccess).
+		Order("replicated_at desc, id desc").
+		First(&replication).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &replication, nil
+}
+
+func ListReplications(limit int) ([]models.Replication, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Order("replicated_at desc, id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var replications []models.Replication
+	if err := query.Find(&replications).Error; err != nil {
+		return nil, err
+	}
+
+	return replications, nil
+}
@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+func CreateRepoCheck(check *models.RepoCheck) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Create(check).Error
+}
+
+func GetLatestRepoCheck(repository string) (*models.RepoCheck, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var check models.RepoCheck
+	if err := database.Where("repository = ?", repository).Order("started_at desc, id desc").First(&check).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &check, nil
+}
+
+// GetLatestSubsetCheck returns the newest check of repository with status
+// that read a data subset, used to continue the rotation.
+func GetLatestSubsetCheck(repository string, status string) (*models.RepoCheck, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var check models.RepoCheck
+	if err := database.Where("repository = ? AND status = ? AND subset_count > 0", repository, status).Order("started_at desc, id desc").First(&check).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &check, nil
+}
+
+func ListRepoChecks(repository string, limit int) ([]models.RepoCheck, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.Order("started_at desc, id desc")
+	if repository != "" {
+		query = query.Where("repository = ?", repository)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var checks []models.RepoCheck
+	if err := query.Find(&checks).Error; err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
@@ -17,14 +17,14 @@ func CreateRepoStat(repoStat models.RepoStat) error {
 	return database.Create(&repoStat).Error
 }
 
-func GetLatestRepoStat() (*models.RepoStat, error) {
+func GetLatestRepoStat(repository string) (*models.RepoStat, error) {
 	database, err := db.Get()
 	if err != nil {
 		return nil, err
 	}
 
 	var repoStat models.RepoStat
-	if err := database.Order("last_refreshed_at desc, id desc").First(&repoStat).Error; err != nil {
+	if err := database.Where("repository = ?", repository).Order("last_refreshed_at desc, id desc").First(&repoStat).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -35,13 +35,13 @@ func GetLatestRepoStat() (*models.RepoStat, error) {
 	return &repoStat, nil
 }
 
-func ListRepoStats(limit int) ([]models.RepoStat, error) {
+func ListRepoStats(repository string, limit int) ([]models.RepoStat, error) {
 	database, err := db.Get()
 	if err != nil {
 		return nil, err
 	}
 
-	query := database.Model(&models.RepoStat{})
+	query := database.Model(&models.RepoStat{}).Where("repository = ?", repository)
 	if limit > 0 {
 		query = query.Order("last_refreshed_at desc, id desc").Limit(limit)
 	}
@@ -27,20 +27,6 @@ func GetSettings() (*models.Setting, error) {
 	return &settings, nil
 }
 
-func UpdatePassword(passwordHash string, needsPasswordChange bool) error {
-	database, err := db.Get()
-	if err != nil {
-		return err
-	}
-
-	return database.Model(&models.Setting{}).
-		Where("id = ?", 1).
-		Updates(map[string]interface{}{
-			"password_hash":         passwordHash,
-			"needs_password_change": needsPasswordChange,
-		}).Error
-}
-
 func UpdateBackupCron(expression string) error {
 	database, err := db.Get()
 	if err != nil {
@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+func ListSLAStates() ([]models.SLAState, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []models.SLAState
+	if err := database.Find(&states).Error; err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// ReplaceSLAStates stores states as the only known states.
+func ReplaceSLAStates(states []models.SLAState) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.SLAState{}).Error; err != nil {
+			return err
+		}
+		if len(states) == 0 {
+			return nil
+		}
+
+		return tx.Create(&states).Error
+	})
+}
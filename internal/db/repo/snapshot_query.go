@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+const (
+	SnapshotSortTime     = "time"
+	SnapshotSortName     = "name"
+	SnapshotSortType     = "type"
+	SnapshotSortBackupID = "backupID"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var snapshotSortColumns = map[string]string{
+	SnapshotSortTime:     "time",
+	SnapshotSortName:     "name",
+	SnapshotSortType:     "snapshot_type",
+	SnapshotSortBackupID: "backup_id",
+}
+
+// SnapshotItem identifies a backed up item of a guest as configured.
+type SnapshotItem struct {
+	VMID         int
+	SnapshotType string
+	SrcFile      string
+}
+
+type SnapshotQuery struct {
+	Repository   string
+	VMID         *int
+	BackupID     string
+	SnapshotType string
+	From         *time.Time
+	To           *time.Time
+	// ExistsInConfig filters on whether the snapshot matches one of
+	// ConfigItems.
+	ExistsInConfig *bool
+	ConfigItems    []SnapshotItem
+	Search         string
+	Sort           string
+	Ascending      bool
+	Cursor         string
+	// Limit of zero returns all matching snapshots.
+	Limit int
+}
+
+type SnapshotPage struct {
+	Snapshots  []models.Snapshot
+	Total      int64
+	NextCursor string
+}
+
+type snapshotCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func IsValidSnapshotSort(sort string) bool {
+	_, ok := snapshotSortColumns[sort]
+	return ok
+}
+
+// QuerySnapshots filters, sorts and pages the snapshot cache in SQL. Pages
+// are addressed by an opaque cursor holding the sort value and ID of the
+// last row, so pages stay stable while new snapshots are added.
+func QuerySnapshots(query SnapshotQuery) (*SnapshotPage, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = SnapshotSortTime
+	}
+	column, ok := snapshotSortColumns[sort]
+	if !ok {
+		return nil, errors.New("invalid sort")
+	}
+
+	filtered := applySnapshotFilters(database.Model(&models.Snapshot{}), query)
+
+	page := &SnapshotPage{}
+	if err := filtered.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	direction := "desc"
+	comparison := "<"
+	if query.Ascending {
+		direction = "asc"
+		comparison = ">"
+	}
+
+	rows := filtered.Session(&gorm.Session{})
+	if query.Cursor != "" {
+		cursor, value, err := decodeSnapshotCursor(query.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		rows = rows.Where("("+column+" "+comparison+" ? OR ("+column+" = ? AND id "+comparison+" ?))", value, value, cursor.ID)
+	}
+	rows = rows.Order(column + " " + direction).Order("id " + direction)
+	if query.Limit > 0 {
+		// one extra row tells whether another page exists
+		rows = rows.Limit(query.Limit + 1)
+	}
+
+	if err := rows.Find(&page.Snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	if query.Limit > 0 && len(page.Snapshots) > query.Limit {
+		page.Snapshots = page.Snapshots[:query.Limit]
+		page.NextCursor = encodeSnapshotCursor(page.Snapshots[len(page.Snapshots)-1], sort)
+	}
+
+	return page, nil
+}
+
+func applySnapshotFilters(tx *gorm.DB, query SnapshotQuery) *gorm.DB {
+	if query.Repository != "" {
+		tx = tx.Where("repository = ?", query.Repository)
+	}
+	if query.VMID != nil {
+		tx = tx.Where("vm_id = ?", *query.VMID)
+	}
+	if query.BackupID != "" {
+		tx = tx.Where("backup_id = ?", query.BackupID)
+	}
+	if query.SnapshotType != "" {
+		tx = tx.Where("snapshot_type = ?", query.SnapshotType)
+	}
+	// snapshot times are stored as UTC text, so bounds must be UTC too to
+	// compare as instants
+	if query.From != nil {
+		tx = tx.Where("time >= ?", query.From.UTC())
+	}
+	if query.To != nil {
+		tx = tx.Where("time <= ?", query.To.UTC())
+	}
+	if search := strings.TrimSpace(query.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		tx = tx.Where("(name LIKE ? ESCAPE '\\' OR snapshot_id LIKE ? ESCAPE '\\' OR backup_id LIKE ? ESCAPE '\\' OR src_file LIKE ? ESCAPE '\\' OR dest_file LIKE ? ESCAPE '\\' OR hostname LIKE ? ESCAPE '\\')",
+			pattern, pattern, pattern, pattern, pattern, pattern)
+	}
+	if query.ExistsInConfig != nil {
+		condition, args := configItemsCondition(query.ConfigItems)
+		if *query.ExistsInConfig {
+			tx = tx.Where(condition, args...)
+		} else {
+			tx = tx.Where("NOT "+condition, args...)
+		}
+	}
+
+	return tx
+}
+
+// configItemsCondition builds a condition matching snapshots of any of items.
+func configItemsCondition(items []SnapshotItem) (string, []interface{}) {
+	if len(items) == 0 {
+		return "(1 = 0)", nil
+	}
+
+	clauses := make([]string, 0, len(items))
+	args := make([]interface{}, 0, len(items)*3)
+	for _, item := range items {
+		clauses = append(clauses, "(vm_id = ? AND snapshot_type = ? AND src_file = ?)")
+		args = append(args, item.VMID, item.SnapshotType, item.SrcFile)
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	return replacer.Replace(value)
+}
+
+func encodeSnapshotCursor(snapshot models.Snapshot, sort string) string {
+	cursor := snapshotCursor{ID: snapshot.ID}
+	switch sort {
+	case SnapshotSortTime:
+		cursor.Value = snapshot.Time.Format(time.RFC3339Nano)
+	case SnapshotSortName:
+		cursor.Value = snapshot.Name
+	case SnapshotSortType:
+		cursor.Value = snapshot.SnapshotType
+	case SnapshotSortBackupID:
+		cursor.Value = snapshot.BackupID
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSnapshotCursor(encoded string, sort string) (*snapshotCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	var cursor snapshotCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	if sort != SnapshotSortTime {
+		return &cursor, cursor.Value, nil
+	}
+
+	value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	return &cursor, value.UTC(), nil
+}
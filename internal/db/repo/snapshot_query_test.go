@@ -0,0 +1,215 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+func seedSnapshots(t *testing.T, repository string, times []time.Time) {
+	t.Helper()
+
+	snapshots := make([]models.Snapshot, 0, len(times))
+	for i, snapshotTime := range times {
+		snapshots = append(snapshots, models.Snapshot{
+			Repository:   repository,
+			SnapshotID:   fmt.Sprintf("%s-%d", repository, i),
+			Time:         snapshotTime,
+			Name:         "guest",
+			SnapshotType: "disk",
+			BackupID:     fmt.Sprintf("backup-%d", i),
+		})
+	}
+	if _, err := SyncSnapshots(repository, snapshots); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuerySnapshotsTimeBounds(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	seedSnapshots(t, "bounds", []time.Time{base, base.Add(2 * time.Hour), base.Add(4 * time.Hour)})
+
+	// base is 08:00 UTC, the snapshots are at 08:00, 10:00 and 12:00 UTC
+	utc := func(hour int) *time.Time {
+		value := time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+		return &value
+	}
+	tests := []struct {
+		name string
+		from *time.Time
+		to   *time.Time
+		want int64
+	}{
+		{name: "no bounds", want: 3},
+		{name: "from between snapshots", from: utc(9), want: 2},
+		{name: "from on a snapshot", from: utc(10), want: 2},
+		{name: "to on a snapshot", to: utc(10), want: 2},
+		{name: "to between snapshots", to: utc(9), want: 1},
+		{name: "from and to", from: utc(9), to: utc(11), want: 1},
+		{name: "after all", from: utc(13), want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := QuerySnapshots(SnapshotQuery{Repository: "bounds", From: test.from, To: test.to})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != test.want || int64(len(page.Snapshots)) != test.want {
+				t.Errorf("got total %d and %d rows, want %d", page.Total, len(page.Snapshots), test.want)
+			}
+		})
+	}
+}
+
+func TestQuerySnapshotsCursorPaging(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	times := []time.Time{
+		base,
+		base.Add(time.Hour),
+		base.Add(time.Hour), // same time, ordered by ID
+		base.Add(2 * time.Hour),
+		base.Add(3 * time.Hour),
+	}
+	seedSnapshots(t, "paging", times)
+
+	for _, ascending := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ascending=%v", ascending), func(t *testing.T) {
+			all, err := QuerySnapshots(SnapshotQuery{Repository: "paging", Ascending: ascending})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var paged []models.Snapshot
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(times) {
+					t.Fatal("paging did not end")
+				}
+				page, err := QuerySnapshots(SnapshotQuery{Repository: "paging", Ascending: ascending, Limit: 2, Cursor: cursor})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.Total != int64(len(times)) {
+					t.Errorf("total = %d, want %d", page.Total, len(times))
+				}
+				paged = append(paged, page.Snapshots...)
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+
+			if len(paged) != len(all.Snapshots) {
+				t.Fatalf("paged %d snapshots, want %d", len(paged), len(all.Snapshots))
+			}
+			for i := range paged {
+				if paged[i].ID != all.Snapshots[i].ID {
+					t.Errorf("row %d: got snapshot %d, want %d", i, paged[i].ID, all.Snapshots[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestQuerySnapshotsInvalidCursor(t *testing.T) {
+	if _, err := QuerySnapshots(SnapshotQuery{Cursor: "not a cursor"}); err != ErrInvalidCursor {
+		t.Errorf("err = %v, want %v", err, ErrInvalidCursor)
+	}
+}
+
+func TestQuerySnapshotsAcrossDSTChange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	previous := time.Local
+	time.Local = berlin
+	t.Cleanup(func() {
+		time.Local = previous
+	})
+
+	// clocks went back from 03:00 CEST to 02:00 CET, so the later snapshot
+	// has the earlier wall clock time
+	first := time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC).In(berlin)
+	second := time.Date(2024, 10, 27, 1, 10, 0, 0, time.UTC).In(berlin)
+	third := time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC).In(berlin)
+	seedSnapshots(t, "dst", []time.Time{first, second, third})
+
+	from := time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC)
+	page, err := QuerySnapshots(SnapshotQuery{Repository: "dst", From: &from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Errorf("from %s matched %d snapshots, want 2", from, page.Total)
+	}
+
+	var paged []time.Time
+	cursor := ""
+	for pages := 0; pages < 3; pages++ {
+		page, err := QuerySnapshots(SnapshotQuery{Repository: "dst", Ascending: true, Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, snapshot := range page.Snapshots {
+			paged = append(paged, snapshot.Time)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	want := []time.Time{first, second, third}
+	if len(paged) != len(want) {
+		t.Fatalf("paged %v, want %v", paged, want)
+	}
+	for i := range want {
+		if !paged[i].Equal(want[i]) {
+			t.Errorf("row %d at %s, want %s", i, paged[i], want[i])
+		}
+	}
+}
+
+func TestSyncSnapshotsNormalizesStoredTimes(t *testing.T) {
+	local := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	legacy := models.Snapshot{Repository: "legacy", SnapshotID: "legacy-0", Time: local, Tags: "[]"}
+	// rows written before times were normalized kept the local offset
+	database, err := db.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := SyncSnapshots("legacy", []models.Snapshot{{SnapshotID: "legacy-0", Time: local, Tags: "[]"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 {
+		t.Errorf("updated %d rows, want 1", result.Updated)
+	}
+
+	var stored string
+	if err := database.Raw("SELECT CAST(time AS TEXT) FROM snapshots WHERE id = ?", legacy.ID).Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !parseSQLiteTime(stored).Equal(local) || !strings.HasSuffix(stored, "+00:00") {
+		t.Errorf("stored time = %s, want %s in UTC", stored, local.UTC())
+	}
+
+	result, err = SyncSnapshots("legacy", []models.Snapshot{{SnapshotID: "legacy-0", Time: local, Tags: "[]"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 0 {
+		t.Errorf("second sync updated %d rows, want 0", result.Updated)
+	}
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+func GetSnapshotStat(repository string, snapshotID string) (*models.SnapshotStat, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var stat models.SnapshotStat
+	if err := database.Where("repository = ? AND snapshot_id = ?", repository, snapshotID).First(&stat).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &stat, nil
+}
+
+func CreateSnapshotStat(stat *models.SnapshotStat) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Create(stat).Error
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,64 +19,261 @@ type SnapshotOverview struct {
 	LatestSnapshot *time.Time `json:"latestSnapshot"`
 }
 
-func ReplaceSnapshots(snapshots []models.Snapshot) error {
+type BackupIDSummary struct {
+	BackupID      string
+	Time          time.Time
+	SnapshotCount int
+}
+
+// ListBackupIDs returns every backup ID cached for repository with the time of
+// its first snapshot, oldest first. A zero since lists all backups.
+func ListBackupIDs(repository string, since time.Time) ([]BackupIDSummary, error) {
 	database, err := db.Get()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	query := database.Model(&models.Snapshot{}).
+		Select("backup_id, MIN(time) AS time, COUNT(*) AS snapshot_count").
+		Where("repository = ? AND backup_id <> ''", repository).
+		Group("backup_id").
+		Order("time asc")
+
+	var rows []struct {
+		BackupID      string
+		Time          string
+		SnapshotCount int
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make([]BackupIDSummary, 0, len(rows))
+	for _, row := range rows {
+		summary := BackupIDSummary{
+			BackupID:      row.BackupID,
+			Time:          parseSQLiteTime(row.Time),
+			SnapshotCount: row.SnapshotCount,
+		}
+		if !since.IsZero() && summary.Time.Before(since) {
+			continue
+		}
+		summaries = append(summaries, summary)
 	}
 
-	return database.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Exec("DELETE FROM snapshots").Error; err != nil {
+	return summaries, nil
+}
+
+type SnapshotSyncResult struct {
+	Added   int
+	Removed int
+	Updated int
+}
+
+// snapshotSyncColumns are rewritten when the tags or the size of a cached
+// snapshot differ from restic's listing, or its time is not stored in UTC yet.
+var snapshotSyncColumns = []string{"time", "tags", "backup_id", "vm_id", "name", "vm_type", "snapshot_type", "backup_date", "dest_file", "src_file", "size", "data_added"}
+
+// SyncSnapshots makes the cache of repository match snapshots, the complete
+// output of `restic snapshots`. Existing rows keep their ID and first seen
+// time.
+func SyncSnapshots(repository string, snapshots []models.Snapshot) (*SnapshotSyncResult, error) {
+	return syncSnapshots(repository, snapshots, true)
+}
+
+// MergeSnapshots adds and updates snapshots from a filtered listing without
+// removing cached snapshots that were not part of it.
+func MergeSnapshots(repository string, snapshots []models.Snapshot) (*SnapshotSyncResult, error) {
+	return syncSnapshots(repository, snapshots, false)
+}
+
+func syncSnapshots(repository string, snapshots []models.Snapshot, complete bool) (*SnapshotSyncResult, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SnapshotSyncResult{}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		var cached []models.Snapshot
+		if err := tx.Select("id", "snapshot_id", "time", "tags", "size").Where("repository = ?", repository).Find(&cached).Error; err != nil {
 			return err
 		}
 
-		if len(snapshots) == 0 {
+		existing := make(map[string]models.Snapshot, len(cached))
+		for _, snapshot := range cached {
+			existing[snapshot.SnapshotID] = snapshot
+		}
+
+		now := time.Now()
+		seen := make(map[string]bool, len(snapshots))
+		var added []models.Snapshot
+		for _, snapshot := range snapshots {
+			seen[snapshot.SnapshotID] = true
+			// times are stored as text, which only compares like the instants
+			// when every row has the same offset
+			snapshot.Time = snapshot.Time.UTC()
+
+			old, ok := existing[snapshot.SnapshotID]
+			if !ok {
+				snapshot.Repository = repository
+				snapshot.FirstSeenAt = now
+				added = append(added, snapshot)
+				continue
+			}
+			if old.Tags == snapshot.Tags && old.Size == snapshot.Size && isUTC(old.Time) {
+				continue
+			}
+
+			snapshot.ID = old.ID
+			if err := tx.Model(&snapshot).Select(snapshotSyncColumns).Updates(&snapshot).Error; err != nil {
+				return err
+			}
+			result.Updated++
+		}
+
+		if len(added) > 0 {
+			if err := tx.Create(&added).Error; err != nil {
+				return err
+			}
+			result.Added = len(added)
+		}
+
+		if !complete {
+			return nil
+		}
+
+		var vanished []string
+		for snapshotID := range existing {
+			if !seen[snapshotID] {
+				vanished = append(vanished, snapshotID)
+			}
+		}
+		if len(vanished) == 0 {
 			return nil
 		}
 
-		return tx.Create(&snapshots).Error
+		if err := tx.Where("repository = ? AND snapshot_id IN ?", repository, vanished).Delete(&models.Snapshot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("repository = ? AND snapshot_id IN ?", repository, vanished).Delete(&models.SnapshotStat{}).Error; err != nil {
+			return err
+		}
+		result.Removed = len(vanished)
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-func ListSnapshots() ([]models.Snapshot, error) {
+// DeleteSnapshotsOutsideRepositories drops cached snapshots of repositories
+// that are no longer configured.
+func DeleteSnapshotsOutsideRepositories(repositories []string) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	query := database.Session(&gorm.Session{AllowGlobalUpdate: true})
+	if len(repositories) > 0 {
+		query = query.Where("repository NOT IN ?", repositories)
+	}
+
+	return query.Delete(&models.Snapshot{}).Error
+}
+
+func ListSnapshots(repository string) ([]models.Snapshot, error) {
 	database, err := db.Get()
 	if err != nil {
 		return nil, err
 	}
 
+	query := database.Order("time desc")
+	if repository != "" {
+		query = query.Where("repository = ?", repository)
+	}
+
 	var snapshots []models.Snapshot
-	if err := database.Order("time desc").Find(&snapshots).Error; err != nil {
+	if err := query.Find(&snapshots).Error; err != nil {
 		return nil, err
 	}
 
 	return snapshots, nil
 }
 
-func GetSnapshotOverview() (*SnapshotOverview, error) {
+func GetSnapshot(id uint) (*models.Snapshot, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.Snapshot
+	if err := database.First(&snapshot, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// FindSnapshot looks up a cached snapshot by its restic ID.
+func FindSnapshot(repository string, snapshotID string) (*models.Snapshot, error) {
 	database, err := db.Get()
 	if err != nil {
 		return nil, err
 	}
 
+	var snapshot models.Snapshot
+	if err := database.Where("repository = ? AND snapshot_id = ?", repository, snapshotID).First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+func GetSnapshotOverview(repository string) (*SnapshotOverview, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := func() *gorm.DB {
+		query := database.Model(&models.Snapshot{})
+		if repository != "" {
+			query = query.Where("repository = ?", repository)
+		}
+		return query
+	}
+
 	overview := &SnapshotOverview{}
-	if err := database.Model(&models.Snapshot{}).Count(&overview.TotalSnapshots).Error; err != nil {
+	if err := scoped().Count(&overview.TotalSnapshots).Error; err != nil {
 		return nil, err
 	}
-	if err := database.Model(&models.Snapshot{}).Distinct("backup_id").Where("backup_id <> ''").Count(&overview.TotalBackups).Error; err != nil {
+	if err := scoped().Distinct("backup_id").Where("backup_id <> ''").Count(&overview.TotalBackups).Error; err != nil {
 		return nil, err
 	}
-	if err := database.Model(&models.Snapshot{}).Distinct("vm_id").Where("vm_id IS NOT NULL").Count(&overview.TotalVMs).Error; err != nil {
+	if err := scoped().Distinct("vm_id").Where("vm_id IS NOT NULL").Count(&overview.TotalVMs).Error; err != nil {
 		return nil, err
 	}
-	if err := database.Model(&models.Snapshot{}).Where("snapshot_type = ?", "disk").Count(&overview.DiskSnapshots).Error; err != nil {
+	if err := scoped().Where("snapshot_type = ?", "disk").Count(&overview.DiskSnapshots).Error; err != nil {
 		return nil, err
 	}
-	if err := database.Model(&models.Snapshot{}).Where("snapshot_type = ?", "config").Count(&overview.Configs).Error; err != nil {
+	if err := scoped().Where("snapshot_type = ?", "config").Count(&overview.Configs).Error; err != nil {
 		return nil, err
 	}
 
 	var latest models.Snapshot
-	if err := database.Order("time desc").First(&latest).Error; err == nil {
+	if err := scoped().Order("time desc").First(&latest).Error; err == nil {
 		overview.LatestSnapshot = &latest.Time
 	}
 
@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"errors"
 	"time"
 
+	"gorm.io/gorm"
+
 	"prostic/internal/db"
 	"prostic/internal/db/models"
 )
@@ -44,3 +47,59 @@ func ListTasks() ([]models.Task, error) {
 
 	return tasks, nil
 }
+
+type TaskCount struct {
+	Purpose string
+	Status  string
+	Count   int64
+}
+
+func CountTasks() ([]TaskCount, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []TaskCount
+	if err := database.Model(&models.Task{}).
+		Select("purpose, status, COUNT(*) AS count").
+		Group("purpose, status").
+		Order("purpose, status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func GetTask(taskID uint) (*models.Task, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var task models.Task
+	if err := database.First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+func ListTasksByStatus(status string) ([]models.Task, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []models.Task
+	if err := database.Where("status = ?", status).Order("started_at asc, id asc").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
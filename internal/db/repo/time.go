@@ -0,0 +1,34 @@
+package repo
+
+import "time"
+
+// sqliteTimeLayouts are the layouts the sqlite driver writes time values in.
+// Aggregates such as MIN(time) come back as plain strings and need parsing.
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func parseSQLiteTime(value string) time.Time {
+	for _, layout := range sqliteTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+
+	return time.Time{}
+}
+
+// isUTC reports whether a stored time was written with a zero offset, as
+// snapshot times are to compare as text in queries.
+func isUTC(t time.Time) bool {
+	_, offset := t.Zone()
+	return offset == 0
+}
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+// ErrLastWithRole is returned when a change would leave no user with the
+// role that has to be kept.
+var ErrLastWithRole = errors.New("no user would be left with the role")
+
+func ListUsers() ([]models.User, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := database.Order("username asc").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func GetUser(userID uint) (*models.User, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := database.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func GetUserByUsername(username string) (*models.User, error) {
+	database, err := db.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := database.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func CreateUser(user *models.User) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Create(user).Error
+}
+
+func UpdateUser(userID uint, updates map[string]interface{}) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
+}
+
+// UpdateUserKeepingRole applies updates unless no user with role would be
+// left. Changing the row first takes the write lock, so concurrent changes
+// count the admins one after another.
+func UpdateUserKeepingRole(userID uint, updates map[string]interface{}, role string) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return ensureUserWithRole(tx, role)
+	})
+}
+
+// DeleteUserKeepingRole deletes a user unless no user with role would be
+// left.
+func DeleteUserKeepingRole(userID uint, role string) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+
+		return ensureUserWithRole(tx, role)
+	})
+}
+
+func ensureUserWithRole(tx *gorm.DB, role string) error {
+	var count int64
+	if err := tx.Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLastWithRole
+	}
+
+	return nil
+}
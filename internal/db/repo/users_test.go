@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"prostic/internal/db"
+	"prostic/internal/db/models"
+)
+
+// seedUsers creates count users with a role named after prefix that is new
+// to the database, so tests can count it on their own.
+func seedUsers(t *testing.T, prefix string, count int) (string, []models.User) {
+	t.Helper()
+
+	role := fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	users := make([]models.User, count)
+	for i := range users {
+		users[i] = models.User{Username: fmt.Sprintf("%s-%d", role, i), PasswordHash: "hash", Role: role}
+		if err := CreateUser(&users[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return role, users
+}
+
+func countRole(t *testing.T, role string) int64 {
+	t.Helper()
+
+	database, err := db.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := database.Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestUpdateUserKeepingRole(t *testing.T) {
+	role, users := seedUsers(t, "update-keeper", 2)
+
+	if err := UpdateUserKeepingRole(users[0].ID, map[string]interface{}{"role": "viewer"}, role); err != nil {
+		t.Fatalf("demoting one of two = %v", err)
+	}
+	err := UpdateUserKeepingRole(users[1].ID, map[string]interface{}{"role": "viewer"}, role)
+	if !errors.Is(err, ErrLastWithRole) {
+		t.Fatalf("demoting the last = %v, want ErrLastWithRole", err)
+	}
+
+	user, err := GetUser(users[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != role {
+		t.Errorf("role after rollback = %s", user.Role)
+	}
+}
+
+func TestDeleteUserKeepingRole(t *testing.T) {
+	role, users := seedUsers(t, "delete-keeper", 2)
+
+	if err := DeleteUserKeepingRole(users[0].ID, role); err != nil {
+		t.Fatalf("deleting one of two = %v", err)
+	}
+	if err := DeleteUserKeepingRole(users[1].ID, role); !errors.Is(err, ErrLastWithRole) {
+		t.Fatalf("deleting the last = %v, want ErrLastWithRole", err)
+	}
+
+	user, err := GetUser(users[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user == nil {
+		t.Error("last user was deleted")
+	}
+}
+
+func TestKeepingRoleConcurrently(t *testing.T) {
+	role, users := seedUsers(t, "concurrent-keeper", 4)
+
+	var wg sync.WaitGroup
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// errors are expected, at least one change has to fail
+			if i%2 == 0 {
+				_ = DeleteUserKeepingRole(user.ID, role)
+				return
+			}
+			_ = UpdateUserKeepingRole(user.ID, map[string]interface{}{"role": "viewer"}, role)
+		}()
+	}
+	wg.Wait()
+
+	if count := countRole(t, role); count < 1 {
+		t.Errorf("%d users left with the role", count)
+	}
+}
@@ -0,0 +1,332 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"prostic/internal/config"
+	"prostic/internal/util"
+)
+
+const (
+	DefaultBinaryPath = "/usr/bin/restic"
+
+	// interruptGracePeriod is how long restic gets to clean up its locks after
+	// SIGINT before it is killed.
+	interruptGracePeriod = 30 * time.Second
+)
+
+// Runner executes restic commands. Services only depend on this interface so
+// tests can swap in a fake via SetFactory.
+type Runner interface {
+	// Run streams stdout of the command into stdout.
+	Run(ctx context.Context, stdout io.Writer, args ...string) error
+	// Output returns stdout of the command.
+	Output(ctx context.Context, args ...string) (string, error)
+	// Stream decodes stdout of a --json command and calls handler for every
+	// message. It is not bound by the client timeout.
+	Stream(ctx context.Context, args []string, handler func(json.RawMessage)) error
+	// WithStderr returns a Runner that passes every stderr line to fn.
+	WithStderr(fn func(line string)) Runner
+}
+
+type Client struct {
+	BinaryPath string
+	Env        map[string]string
+	// Timeout caps Run and Output when the context has no deadline of its
+	// own; zero runs commands without a limit.
+	Timeout time.Duration
+	Stderr  func(line string)
+}
+
+var (
+	factoryMu sync.Mutex
+	factory   func(repository config.Repository) Runner
+)
+
+func NewClient(binaryPath string, env map[string]string, timeout time.Duration) *Client {
+	if binaryPath == "" {
+		binaryPath = DefaultBinaryPath
+	}
+
+	return &Client{
+		BinaryPath: binaryPath,
+		Env:        env,
+		Timeout:    timeout,
+	}
+}
+
+// NewClientFromConfig applies restic.timeout, which is unset by default so
+// commands whose duration grows with the repository are not cut off.
+func NewClientFromConfig(cfg config.Restic, repository config.Repository) *Client {
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		if parsed, err := time.ParseDuration(cfg.Timeout); err == nil {
+			timeout = parsed
+		}
+	}
+
+	return NewClient(cfg.Binary, repository.Env, timeout)
+}
+
+// BinaryPath returns the restic binary the clients run.
+func BinaryPath() string {
+	if cfg := config.Get(); cfg != nil && cfg.Restic.Binary != "" {
+		return cfg.Restic.Binary
+	}
+
+	return DefaultBinaryPath
+}
+
+// ForRepository returns a runner for the named repository. An empty name
+// selects the default repository.
+func ForRepository(name string) (Runner, error) {
+	repository := config.FindRepository(name)
+	if repository == nil {
+		if name == "" {
+			return nil, errors.New("no restic repository configured")
+		}
+		return nil, fmt.Errorf("unknown restic repository %q", name)
+	}
+
+	factoryMu.Lock()
+	fn := factory
+	factoryMu.Unlock()
+	if fn != nil {
+		return fn(*repository), nil
+	}
+
+	return NewClientFromConfig(config.Get().Restic, *repository), nil
+}
+
+// ForCopy returns a runner for target that also carries the credentials of
+// source as RESTIC_FROM_* variables, as needed by `restic copy`. Backend
+// credentials that are not repository-specific (e.g. AWS_*) are taken from
+// source only when target does not set them.
+func ForCopy(source string, target string) (Runner, error) {
+	sourceRepository := config.FindRepository(source)
+	if sourceRepository == nil {
+		return nil, fmt.Errorf("unknown restic repository %q", source)
+	}
+	targetRepository := config.FindRepository(target)
+	if targetRepository == nil {
+		return nil, fmt.Errorf("unknown restic repository %q", target)
+	}
+
+	env := make(map[string]string, len(sourceRepository.Env)+len(targetRepository.Env))
+	for key, val := range sourceRepository.Env {
+		if fromKey, ok := copySourceEnv[key]; ok {
+			env[fromKey] = val
+		} else if _, ok := targetRepository.Env[key]; !ok {
+			env[key] = val
+		}
+	}
+	for key, val := range targetRepository.Env {
+		env[key] = val
+	}
+
+	merged := config.Repository{Name: targetRepository.Name, Env: env}
+	factoryMu.Lock()
+	fn := factory
+	factoryMu.Unlock()
+	if fn != nil {
+		return fn(merged), nil
+	}
+
+	return NewClientFromConfig(config.Get().Restic, merged), nil
+}
+
+var copySourceEnv = map[string]string{
+	"RESTIC_REPOSITORY":       "RESTIC_FROM_REPOSITORY",
+	"RESTIC_REPOSITORY_FILE":  "RESTIC_FROM_REPOSITORY_FILE",
+	"RESTIC_PASSWORD":         "RESTIC_FROM_PASSWORD",
+	"RESTIC_PASSWORD_FILE":    "RESTIC_FROM_PASSWORD_FILE",
+	"RESTIC_PASSWORD_COMMAND": "RESTIC_FROM_PASSWORD_COMMAND",
+	"RESTIC_KEY_HINT":         "RESTIC_FROM_KEY_HINT",
+}
+
+// SetFactory replaces how runners are built for a repository; nil restores
+// the real restic client.
+func SetFactory(fn func(repository config.Repository) Runner) {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	factory = fn
+}
+
+func (c *Client) WithStderr(fn func(line string)) Runner {
+	clone := *c
+	clone.Stderr = fn
+	return &clone
+}
+
+func (c *Client) Run(ctx context.Context, stdout io.Writer, args ...string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	cmd := c.command(ctx, nil, args...)
+	cmd.Stdout = stdout
+
+	return c.wait(ctx, cmd)
+}
+
+func (c *Client) Output(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := c.command(ctx, nil, args...)
+	cmd.Stdout = &stdout
+
+	err := c.wait(ctx, cmd)
+	return stdout.String(), err
+}
+
+func (c *Client) Stream(ctx context.Context, args []string, handler func(json.RawMessage)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	hasJSON := false
+	for _, a := range args {
+		if a == "--json" {
+			hasJSON = true
+			break
+		}
+	}
+	if !hasJSON {
+		return fmt.Errorf("restic JSON stream requires an argument --json")
+	}
+
+	cmd := c.command(ctx, []string{"RESTIC_PROGRESS_FPS=1"}, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr := &stderrCollector{fn: c.Stderr}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(stdout)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return fmt.Errorf("json decode error: %w", err)
+		}
+		handler(raw)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return stderr.wrap(ctx, err)
+	}
+	return nil
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, hasDeadline := ctx.Deadline(); hasDeadline || c.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.Timeout)
+}
+
+func (c *Client) command(ctx context.Context, extraEnv []string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.BinaryPath, args...)
+	cmd.Env = os.Environ()
+	for key, val := range c.Env {
+		cmd.Env = append(cmd.Env, key+"="+val)
+	}
+	cmd.Env = append(cmd.Env, extraEnv...)
+
+	// restic removes its own locks when interrupted, so prefer that over a kill
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = interruptGracePeriod
+
+	return cmd
+}
+
+func (c *Client) wait(ctx context.Context, cmd *exec.Cmd) error {
+	stderr := &stderrCollector{fn: c.Stderr}
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return stderr.wrap(ctx, err)
+	}
+
+	return nil
+}
+
+// stderrCollector keeps stderr for error messages and forwards complete lines
+// to fn as they arrive.
+type stderrCollector struct {
+	mu      sync.Mutex
+	fn      func(line string)
+	buf     bytes.Buffer
+	partial []byte
+}
+
+func (s *stderrCollector) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf.Write(p)
+	if s.fn == nil {
+		return len(p), nil
+	}
+
+	s.partial = append(s.partial, p...)
+	for {
+		idx := bytes.IndexByte(s.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		s.fn(util.Redact(strings.TrimRight(string(s.partial[:idx]), "\r")))
+		s.partial = s.partial[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+func (s *stderrCollector) wrap(ctx context.Context, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("restic command timed out: %w", err)
+	}
+
+	message := strings.TrimSpace(s.buf.String())
+	if message == "" {
+		return fmt.Errorf("restic command failed: %w", err)
+	}
+
+	lines := strings.Split(message, "\n")
+	if len(lines) > 5 {
+		lines = lines[len(lines)-5:]
+	}
+
+	return fmt.Errorf("restic command failed: %w: %s", err, util.Redact(strings.Join(lines, "\n")))
+}
@@ -0,0 +1,48 @@
+package restic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"prostic/internal/config"
+)
+
+func TestClientTimeout(t *testing.T) {
+	callerDeadline, cancel := context.WithTimeout(context.Background(), 24*time.Hour)
+	defer cancel()
+
+	tests := []struct {
+		name         string
+		timeout      string
+		ctx          context.Context
+		wantDeadline time.Duration
+	}{
+		{name: "no limit by default", ctx: context.Background()},
+		{name: "configured limit", timeout: "2h", ctx: context.Background(), wantDeadline: 2 * time.Hour},
+		{name: "caller deadline replaces the limit", timeout: "2h", ctx: callerDeadline, wantDeadline: 24 * time.Hour},
+		{name: "invalid limit is ignored", timeout: "soon", ctx: context.Background()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewClientFromConfig(config.Restic{Timeout: test.timeout}, config.Repository{Name: "local"})
+			ctx, cancel := client.withTimeout(test.ctx)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if test.wantDeadline == 0 {
+				if ok {
+					t.Errorf("deadline in %v, want none", time.Until(deadline).Round(time.Minute))
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("no deadline, want one in %v", test.wantDeadline)
+			}
+			if remaining := time.Until(deadline); remaining > test.wantDeadline || remaining < test.wantDeadline-time.Minute {
+				t.Errorf("deadline in %v, want %v", remaining.Round(time.Minute), test.wantDeadline)
+			}
+		})
+	}
+}
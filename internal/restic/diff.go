@@ -0,0 +1,57 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type DiffCounts struct {
+	Files     int   `json:"files"`
+	Dirs      int   `json:"dirs"`
+	Others    int   `json:"others"`
+	DataBlobs int   `json:"data_blobs"`
+	TreeBlobs int   `json:"tree_blobs"`
+	Bytes     int64 `json:"bytes"`
+}
+
+type DiffStatistics struct {
+	SourceSnapshot string     `json:"source_snapshot"`
+	TargetSnapshot string     `json:"target_snapshot"`
+	ChangedFiles   int        `json:"changed_files"`
+	Added          DiffCounts `json:"added"`
+	Removed        DiffCounts `json:"removed"`
+}
+
+// Diff compares two snapshots of the same repository. Added and removed
+// count blobs that are referenced by only one of them, which for block images
+// is the amount of changed data.
+func Diff(ctx context.Context, runner Runner, from string, to string) (*DiffStatistics, error) {
+	var stats *DiffStatistics
+	err := runner.Stream(ctx, []string{"diff", "--json", from, to}, func(raw json.RawMessage) {
+		var msg struct {
+			MessageType string `json:"message_type"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil || msg.MessageType != "statistics" {
+			return
+		}
+
+		var parsed DiffStatistics
+		if err := json.Unmarshal(raw, &parsed); err == nil {
+			stats = &parsed
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("restic diff returned no statistics")
+	}
+
+	return stats, nil
+}
+
+// Dump returns the content of path in the snapshot.
+func Dump(ctx context.Context, runner Runner, snapshotID string, path string) (string, error) {
+	return runner.Output(ctx, "dump", snapshotID, path)
+}
@@ -0,0 +1,50 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type Key struct {
+	Current  bool   `json:"current"`
+	ID       string `json:"id"`
+	UserName string `json:"userName"`
+	HostName string `json:"hostName"`
+	Created  string `json:"created"`
+}
+
+func ListKeys(ctx context.Context, runner Runner) ([]Key, error) {
+	out, err := runner.Output(ctx, "key", "list", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal([]byte(out), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// AddKey adds a key whose password is read from newPasswordFile. Empty host
+// and user fall back to restic's defaults.
+func AddKey(ctx context.Context, runner Runner, newPasswordFile string, host string, user string) (string, error) {
+	args := []string{"key", "add", "--new-password-file", newPasswordFile}
+	if host != "" {
+		args = append(args, "--host", host)
+	}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+
+	return runner.Output(ctx, args...)
+}
+
+func RemoveKey(ctx context.Context, runner Runner, keyID string) (string, error) {
+	return runner.Output(ctx, "key", "remove", keyID)
+}
+
+// ChangePassword replaces the password of the key currently in use.
+func ChangePassword(ctx context.Context, runner Runner, newPasswordFile string) (string, error) {
+	return runner.Output(ctx, "key", "passwd", "--new-password-file", newPasswordFile)
+}
@@ -0,0 +1,57 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type Lock struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Exclusive bool      `json:"exclusive"`
+	Hostname  string    `json:"hostname"`
+	Username  string    `json:"username"`
+	PID       int       `json:"pid"`
+	UID       int       `json:"uid"`
+	GID       int       `json:"gid"`
+}
+
+// ListLocks returns every lock in the repository without adding one itself.
+func ListLocks(ctx context.Context, runner Runner) ([]Lock, error) {
+	out, err := runner.Output(ctx, "list", "locks", "--no-lock")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := strings.Fields(out)
+	locks := make([]Lock, 0, len(ids))
+	for _, id := range ids {
+		raw, err := runner.Output(ctx, "cat", "lock", id, "--no-lock")
+		if err != nil {
+			// the lock may have been released between both calls
+			continue
+		}
+
+		lock := Lock{ID: id}
+		if err := json.Unmarshal([]byte(raw), &lock); err != nil {
+			return nil, err
+		}
+		lock.ID = id
+		locks = append(locks, lock)
+	}
+
+	return locks, nil
+}
+
+// Unlock removes stale locks. With removeAll every lock is removed, including
+// those of processes that may still be running.
+func Unlock(ctx context.Context, runner Runner, removeAll bool) (string, error) {
+	args := []string{"unlock"}
+	if removeAll {
+		args = append(args, "--remove-all")
+	}
+
+	return runner.Output(ctx, args...)
+}
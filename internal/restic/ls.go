@@ -0,0 +1,45 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type Node struct {
+	Name  string    `json:"name"`
+	Type  string    `json:"type"`
+	Path  string    `json:"path"`
+	Size  int64     `json:"size"`
+	Mode  uint32    `json:"mode"`
+	MTime time.Time `json:"mtime"`
+}
+
+// ListFiles returns every node of a snapshot. The leading snapshot message
+// is skipped; restic 0.17 renamed struct_type to message_type, both are
+// accepted.
+func ListFiles(ctx context.Context, runner Runner, snapshotID string) ([]Node, error) {
+	nodes := make([]Node, 0)
+	err := runner.Stream(ctx, []string{"ls", "--json", snapshotID}, func(raw json.RawMessage) {
+		var msg struct {
+			StructType  string `json:"struct_type"`
+			MessageType string `json:"message_type"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return
+		}
+		if msg.StructType != "node" && msg.MessageType != "node" {
+			return
+		}
+
+		var node Node
+		if err := json.Unmarshal(raw, &node); err == nil {
+			nodes = append(nodes, node)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
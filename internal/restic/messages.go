@@ -0,0 +1,119 @@
+package restic
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	MessageTypeStatus        = "status"
+	MessageTypeSummary       = "summary"
+	MessageTypeError         = "error"
+	MessageTypeVerboseStatus = "verbose_status"
+)
+
+type StatusMessage struct {
+	SecondsElapsed   int64    `json:"seconds_elapsed"`
+	SecondsRemaining int64    `json:"seconds_remaining"`
+	PercentDone      float64  `json:"percent_done"`
+	TotalFiles       int64    `json:"total_files"`
+	FilesDone        int64    `json:"files_done"`
+	TotalBytes       int64    `json:"total_bytes"`
+	BytesDone        int64    `json:"bytes_done"`
+	ErrorCount       int64    `json:"error_count"`
+	CurrentFiles     []string `json:"current_files"`
+}
+
+type SummaryMessage struct {
+	FilesNew            int64   `json:"files_new"`
+	FilesChanged        int64   `json:"files_changed"`
+	FilesUnmodified     int64   `json:"files_unmodified"`
+	DirsNew             int64   `json:"dirs_new"`
+	DirsChanged         int64   `json:"dirs_changed"`
+	DirsUnmodified      int64   `json:"dirs_unmodified"`
+	DataBlobs           int64   `json:"data_blobs"`
+	TreeBlobs           int64   `json:"tree_blobs"`
+	DataAdded           int64   `json:"data_added"`
+	DataAddedPacked     int64   `json:"data_added_packed"`
+	TotalFilesProcessed int64   `json:"total_files_processed"`
+	TotalBytesProcessed int64   `json:"total_bytes_processed"`
+	TotalDuration       float64 `json:"total_duration"`
+	SnapshotID          string  `json:"snapshot_id"`
+}
+
+type ErrorMessage struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	During string `json:"during"`
+	Item   string `json:"item"`
+
+	// Message is set by older restic versions instead of Error.
+	Message string `json:"message"`
+}
+
+func (m ErrorMessage) Text() string {
+	text := m.Error.Message
+	if text == "" {
+		text = m.Message
+	}
+	if m.Item != "" {
+		text = m.Item + ": " + text
+	}
+
+	return text
+}
+
+type VerboseStatusMessage struct {
+	Action       string  `json:"action"`
+	Item         string  `json:"item"`
+	Duration     float64 `json:"duration"`
+	DataSize     int64   `json:"data_size"`
+	MetadataSize int64   `json:"metadata_size"`
+	TotalFiles   int64   `json:"total_files"`
+}
+
+// BackupMessage is one line of `restic backup --json` output. Exactly one of
+// the typed fields is set, matching Type; unknown types only carry Raw.
+type BackupMessage struct {
+	Type          string
+	Status        *StatusMessage
+	Summary       *SummaryMessage
+	Error         *ErrorMessage
+	VerboseStatus *VerboseStatusMessage
+	Raw           json.RawMessage
+}
+
+func ParseBackupMessage(raw json.RawMessage) (BackupMessage, error) {
+	var envelope struct {
+		MessageType string `json:"message_type"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return BackupMessage{}, err
+	}
+
+	msg := BackupMessage{Type: envelope.MessageType, Raw: raw}
+	var target interface{}
+	switch envelope.MessageType {
+	case MessageTypeStatus:
+		msg.Status = &StatusMessage{}
+		target = msg.Status
+	case MessageTypeSummary:
+		msg.Summary = &SummaryMessage{}
+		target = msg.Summary
+	case MessageTypeError:
+		msg.Error = &ErrorMessage{}
+		target = msg.Error
+	case MessageTypeVerboseStatus:
+		msg.VerboseStatus = &VerboseStatusMessage{}
+		target = msg.VerboseStatus
+	default:
+		return msg, nil
+	}
+
+	if err := json.Unmarshal(raw, target); err != nil {
+		return BackupMessage{}, fmt.Errorf("invalid restic %s message: %w", envelope.MessageType, err)
+	}
+
+	return msg, nil
+}
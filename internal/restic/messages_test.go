@@ -0,0 +1,194 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Lines as printed by `restic backup --json` 0.16 and 0.17.
+const (
+	statusLine        = `{"message_type":"status","seconds_elapsed":12,"seconds_remaining":36,"percent_done":0.25,"total_files":1,"files_done":0,"total_bytes":34359738368,"bytes_done":8589934592,"current_files":["/vm-100-disk-0.raw"]}`
+	stdinStatusLine   = `{"message_type":"status","seconds_elapsed":3,"percent_done":0,"total_files":1,"bytes_done":104857600,"current_files":["/vm-100-disk-0.raw"]}`
+	summaryLine       = `{"message_type":"summary","files_new":1,"files_changed":0,"files_unmodified":0,"dirs_new":0,"dirs_changed":0,"dirs_unmodified":0,"data_blobs":2142,"tree_blobs":1,"data_added":1145321472,"data_added_packed":482345123,"total_files_processed":1,"total_bytes_processed":34359738368,"total_duration":412.83,"backup_start":"2024-05-01T10:00:00.123+02:00","backup_end":"2024-05-01T10:06:52.953+02:00","snapshot_id":"8d0a2f3c1e5b47a9b6c8d0e2f4a6b8c0d2e4f6a8b0c2d4e6f8a0b2c4d6e8f0a2"}`
+	errorLine         = `{"message_type":"error","error":{"message":"read /dev/pve/vm-100-disk-0: input/output error"},"during":"archival","item":"/vm-100-disk-0.raw"}`
+	legacyErrorLine   = `{"message_type":"error","error":{},"message":"snapshot is empty","during":"archival","item":""}`
+	verboseStatusLine = `{"message_type":"verbose_status","action":"new","item":"/vm-100-disk-0.raw","duration":412.8,"data_size":34359738368,"metadata_size":0,"total_files":1}`
+	exitErrorLine     = `{"message_type":"exit_error","code":1,"message":"Fatal: unable to open repository"}`
+)
+
+func TestParseBackupMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    BackupMessage
+		wantErr bool
+	}{
+		{
+			name: "status",
+			line: statusLine,
+			want: BackupMessage{Type: MessageTypeStatus, Status: &StatusMessage{
+				SecondsElapsed:   12,
+				SecondsRemaining: 36,
+				PercentDone:      0.25,
+				TotalFiles:       1,
+				TotalBytes:       34359738368,
+				BytesDone:        8589934592,
+				CurrentFiles:     []string{"/vm-100-disk-0.raw"},
+			}},
+		},
+		{
+			name: "status from stdin without total",
+			line: stdinStatusLine,
+			want: BackupMessage{Type: MessageTypeStatus, Status: &StatusMessage{
+				SecondsElapsed: 3,
+				TotalFiles:     1,
+				BytesDone:      104857600,
+				CurrentFiles:   []string{"/vm-100-disk-0.raw"},
+			}},
+		},
+		{
+			name: "summary",
+			line: summaryLine,
+			want: BackupMessage{Type: MessageTypeSummary, Summary: &SummaryMessage{
+				FilesNew:            1,
+				DataBlobs:           2142,
+				TreeBlobs:           1,
+				DataAdded:           1145321472,
+				DataAddedPacked:     482345123,
+				TotalFilesProcessed: 1,
+				TotalBytesProcessed: 34359738368,
+				TotalDuration:       412.83,
+				SnapshotID:          "8d0a2f3c1e5b47a9b6c8d0e2f4a6b8c0d2e4f6a8b0c2d4e6f8a0b2c4d6e8f0a2",
+			}},
+		},
+		{
+			name: "verbose status",
+			line: verboseStatusLine,
+			want: BackupMessage{Type: MessageTypeVerboseStatus, VerboseStatus: &VerboseStatusMessage{
+				Action:     "new",
+				Item:       "/vm-100-disk-0.raw",
+				Duration:   412.8,
+				DataSize:   34359738368,
+				TotalFiles: 1,
+			}},
+		},
+		{
+			name: "unknown type",
+			line: exitErrorLine,
+			want: BackupMessage{Type: "exit_error"},
+		},
+		{
+			name:    "field of the wrong type",
+			line:    `{"message_type":"status","percent_done":"25%"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			line:    `"scanning"`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseBackupMessage(json.RawMessage(test.line))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseBackupMessage() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got.Raw) != test.line {
+				t.Errorf("Raw = %s", got.Raw)
+			}
+			got.Raw = nil
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseBackupMessage() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessageText(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "error object", line: errorLine, want: "/vm-100-disk-0.raw: read /dev/pve/vm-100-disk-0: input/output error"},
+		{name: "message of older versions", line: legacyErrorLine, want: "snapshot is empty"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := ParseBackupMessage(json.RawMessage(test.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Error == nil {
+				t.Fatalf("Error is not set for %s", msg.Type)
+			}
+			if got := msg.Error.Text(); got != test.want {
+				t.Errorf("Text() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// streamRunner replays lines as the stdout of a --json command.
+type streamRunner struct {
+	lines []string
+}
+
+func (r streamRunner) Run(ctx context.Context, stdout io.Writer, args ...string) error {
+	return nil
+}
+
+func (r streamRunner) Output(ctx context.Context, args ...string) (string, error) {
+	return "", nil
+}
+
+func (r streamRunner) Stream(ctx context.Context, args []string, handler func(json.RawMessage)) error {
+	for _, line := range r.lines {
+		handler(json.RawMessage(line))
+	}
+	return nil
+}
+
+func (r streamRunner) WithStderr(fn func(line string)) Runner {
+	return r
+}
+
+func TestBackupForwardsUnparsedLines(t *testing.T) {
+	runner := streamRunner{lines: []string{
+		statusLine,
+		`{"message_type":"summary","data_added":"1 GiB"}`,
+		exitErrorLine,
+		summaryLine,
+	}}
+
+	var types []string
+	var unparsed []string
+	err := Backup(context.Background(), runner, []string{"backup", "--json"}, func(msg BackupMessage) {
+		types = append(types, msg.Type)
+	}, func(line string) {
+		unparsed = append(unparsed, line)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{MessageTypeStatus, "exit_error", MessageTypeSummary}; !reflect.DeepEqual(types, want) {
+		t.Errorf("handled %v, want %v", types, want)
+	}
+	if len(unparsed) != 1 || !strings.Contains(unparsed[0], `"data_added":"1 GiB"`) || !strings.Contains(unparsed[0], "invalid restic summary message") {
+		t.Errorf("unparsed = %q", unparsed)
+	}
+}
@@ -0,0 +1,100 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type RepositoryStatus string
+
+const (
+	RepositoryOK             RepositoryStatus = "ok"
+	RepositoryUnreachable    RepositoryStatus = "unreachable"
+	RepositoryNotInitialized RepositoryStatus = "not initialized"
+	RepositoryWrongPassword  RepositoryStatus = "wrong password"
+	RepositoryLocked         RepositoryStatus = "locked"
+)
+
+// Exit codes used by restic 0.17 and newer.
+const (
+	exitRepositoryMissing = 10
+	exitLockFailed        = 11
+	exitWrongPassword     = 12
+)
+
+type RepositoryState struct {
+	Status  RepositoryStatus `json:"status"`
+	Message string           `json:"message,omitempty"`
+}
+
+type InitOptions struct {
+	// RepositoryVersion is passed as --repository-version; 1 disables
+	// compression support. Zero uses restic's default.
+	RepositoryVersion int
+	// CopyChunkerParams copies the chunker parameters of the RESTIC_FROM_*
+	// repository, which keeps deduplication working across `restic copy`.
+	CopyChunkerParams bool
+}
+
+// GetRepositoryState probes the repository without taking a lock and
+// classifies the outcome. A repository holding locks reports RepositoryLocked.
+func GetRepositoryState(ctx context.Context, runner Runner) RepositoryState {
+	if _, err := runner.Output(ctx, "cat", "config"); err != nil {
+		return RepositoryState{Status: classifyError(err), Message: err.Error()}
+	}
+
+	out, err := runner.Output(ctx, "list", "locks", "--no-lock")
+	if err != nil {
+		return RepositoryState{Status: classifyError(err), Message: err.Error()}
+	}
+	if locks := strings.Fields(out); len(locks) > 0 {
+		return RepositoryState{Status: RepositoryLocked, Message: strconv.Itoa(len(locks)) + " lock(s) present"}
+	}
+
+	return RepositoryState{Status: RepositoryOK}
+}
+
+func Init(ctx context.Context, runner Runner, options InitOptions) (string, error) {
+	args := []string{"init"}
+	if options.RepositoryVersion > 0 {
+		args = append(args, "--repository-version", strconv.Itoa(options.RepositoryVersion))
+	}
+	if options.CopyChunkerParams {
+		args = append(args, "--copy-chunker-params")
+	}
+
+	return runner.Output(ctx, args...)
+}
+
+func classifyError(err error) RepositoryStatus {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitCode() {
+		case exitRepositoryMissing:
+			return RepositoryNotInitialized
+		case exitLockFailed:
+			return RepositoryLocked
+		case exitWrongPassword:
+			return RepositoryWrongPassword
+		}
+	}
+
+	// older restic versions exit with 1 for everything, so fall back to the
+	// error text
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "wrong password"):
+		return RepositoryWrongPassword
+	case strings.Contains(message, "is there a repository at the following location"),
+		strings.Contains(message, "repository does not exist"),
+		strings.Contains(message, "unable to open config file"):
+		return RepositoryNotInitialized
+	case strings.Contains(message, "already locked"):
+		return RepositoryLocked
+	}
+
+	return RepositoryUnreachable
+}
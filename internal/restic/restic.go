@@ -1,15 +1,13 @@
 package restic
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
-	"os/exec"
-	"prostic/internal/config"
 	"strings"
 	"time"
+
+	"prostic/internal/util"
 )
 
 type Snapshot struct {
@@ -19,7 +17,22 @@ type Snapshot struct {
 	Paths    []string  `json:"paths"`
 	Hostname string    `json:"hostname"`
 	Tree     string    `json:"tree"`
+	Parent   string    `json:"parent"`
+	// Summary is only present for snapshots created by restic 0.17 or newer.
+	Summary *SnapshotSummary `json:"summary"`
+}
+
+type SnapshotSummary struct {
+	DataAdded           int64 `json:"data_added"`
+	TotalFilesProcessed int64 `json:"total_files_processed"`
+	TotalBytesProcessed int64 `json:"total_bytes_processed"`
 }
+
+const (
+	StatsModeRestoreSize = "restore-size"
+	StatsModeRawData     = "raw-data"
+)
+
 type Stats struct {
 	TotalSize              int64   `json:"total_size"`
 	TotalUncompressedSize  int64   `json:"total_uncompressed_size"`
@@ -27,122 +40,22 @@ type Stats struct {
 	CompressionProgress    int     `json:"compression_progress"`
 	CompressionSpaceSaving float64 `json:"compression_space_saving"`
 	TotalBlobCount         int64   `json:"total_blob_count"`
+	TotalFileCount         int64   `json:"total_file_count"`
 	SnapshotsCount         int64   `json:"snapshots_count"`
 }
 
-func RunResticCommand(showOutput bool, args ...string) error {
-	env := os.Environ()
-	for key, val := range config.Get().Restic.EnvVars {
-		env = append(env, key+"="+val)
-	}
-
-	cmd := exec.Command("/usr/bin/restic", args...)
-	cmd.Env = env
-
-	if showOutput {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("restic command failed: %v", err)
-		}
-		return nil
-	}
-
-	_, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("restic command failed: %v", err)
-	}
-
-	return nil
-}
-func RunResticOutput(args ...string) (string, error) {
-	env := os.Environ()
-	for key, val := range config.Get().Restic.EnvVars {
-		env = append(env, key+"="+val)
-	}
-	cmd := exec.Command("/usr/bin/restic", args...)
-	cmd.Env = env
-
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return string(out), fmt.Errorf("restic command failed: %v", err)
-	}
-	return string(out), nil
+func GetSnapshots(ctx context.Context, runner Runner) ([]Snapshot, error) {
+	return GetSnapshotsWithTags(ctx, runner, nil)
 }
-func RunResticJSONStream(args []string, handler func(map[string]interface{})) error {
-	hasJSON := false
-	for _, a := range args {
-		if a == "--json" {
-			hasJSON = true
-			break
-		}
-	}
-	if !hasJSON {
-		return fmt.Errorf("restic JSON stream requires an argument --json")
-	}
-
-	cmd := exec.Command("/usr/bin/restic", args...)
-	e := os.Environ()
-	for key, val := range config.Get().Restic.EnvVars {
-		e = append(e, key+"="+val)
-	}
-	e = append(e, "RESTIC_PROGRESS_FPS=1")
-	cmd.Env = e
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return err
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return err
-	}
-
-	if err := cmd.Start(); err != nil {
-		return err
-	}
 
-	go func() {
-		s := bufio.NewScanner(stderr)
-		for s.Scan() {
-			line := s.Text()
-
-			// skip unrelated messages for clean output
-			if strings.HasPrefix(line, "subprocess /bin/dd:") {
-				continue
-			}
-			if strings.Contains(line, "using parent snapshot") {
-				continue
-			}
-			if strings.Contains(line, "old cache directories") {
-				continue
-			}
-			fmt.Println(line)
-		}
-	}()
-
-	dec := json.NewDecoder(stdout)
-
-	for {
-		var obj map[string]interface{}
-		err := dec.Decode(&obj)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("json decode error: %w", err)
-		}
-		handler(obj)
-	}
-
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("restic command failed: %w", err)
+// GetSnapshotsWithTags lists only snapshots carrying all of tags.
+func GetSnapshotsWithTags(ctx context.Context, runner Runner, tags []string) ([]Snapshot, error) {
+	args := []string{"snapshots", "--json"}
+	if len(tags) > 0 {
+		args = append(args, "--tag", strings.Join(tags, ","))
 	}
-	return nil
-}
 
-func GetSnapshots() ([]Snapshot, error) {
-	out, err := RunResticOutput("snapshots", "--json")
+	out, err := runner.Output(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -154,8 +67,22 @@ func GetSnapshots() ([]Snapshot, error) {
 	return snaps, nil
 }
 
-func GetStats() (*Stats, error) {
-	out, err := RunResticOutput("stats", "--mode", "raw-data", "--json")
+func GetStats(ctx context.Context, runner Runner) (*Stats, error) {
+	return getStats(ctx, runner, StatsModeRawData, "")
+}
+
+// GetSnapshotStats returns the stats of a single snapshot in the given mode.
+func GetSnapshotStats(ctx context.Context, runner Runner, mode string, snapshotID string) (*Stats, error) {
+	return getStats(ctx, runner, mode, snapshotID)
+}
+
+func getStats(ctx context.Context, runner Runner, mode string, snapshotID string) (*Stats, error) {
+	args := []string{"stats", "--mode", mode, "--json"}
+	if snapshotID != "" {
+		args = append(args, snapshotID)
+	}
+
+	out, err := runner.Output(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -167,10 +94,48 @@ func GetStats() (*Stats, error) {
 	return &s, nil
 }
 
-func DeleteSnapshots(snapshotIDs []string) (string, error) {
+func DeleteSnapshots(ctx context.Context, runner Runner, snapshotIDs []string) (string, error) {
 	args := make([]string, 0, len(snapshotIDs)+2)
 	args = append(args, "forget", "--prune")
 	args = append(args, snapshotIDs...)
 
-	return RunResticOutput(args...)
+	return runner.Output(ctx, args...)
+}
+
+// Copy copies all snapshots carrying every one of tags from the repository
+// configured via RESTIC_FROM_* into the runner's repository.
+func Copy(ctx context.Context, runner Runner, tags []string) (string, error) {
+	args := []string{"copy"}
+	if len(tags) > 0 {
+		args = append(args, "--tag", strings.Join(tags, ","))
+	}
+
+	return runner.Output(ctx, args...)
+}
+
+// Check verifies the repository structure. A non-empty readDataSubset is
+// passed as --read-data-subset, e.g. "3/25" or "5%".
+func Check(ctx context.Context, runner Runner, readDataSubset string) (string, error) {
+	args := []string{"check"}
+	if readDataSubset != "" {
+		args = append(args, "--read-data-subset="+readDataSubset)
+	}
+
+	return runner.Output(ctx, args...)
+}
+
+// Backup runs `restic backup --json` and hands every parsed message to
+// handler. Lines that do not match their declared message type are passed to
+// unparsed with the reason, so a changed output format shows up in the log.
+func Backup(ctx context.Context, runner Runner, args []string, handler func(BackupMessage), unparsed func(line string)) error {
+	return runner.Stream(ctx, args, func(raw json.RawMessage) {
+		msg, err := ParseBackupMessage(raw)
+		if err != nil {
+			if unparsed != nil {
+				unparsed(fmt.Sprintf("unparsed restic output (%v): %s", err, util.Redact(string(raw))))
+			}
+			return
+		}
+		handler(msg)
+	})
 }
@@ -0,0 +1,107 @@
+// Package restictest provides a fake restic runner for service tests.
+package restictest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"prostic/internal/config"
+	"prostic/internal/restic"
+)
+
+// Call is one restic command run through the fake.
+type Call struct {
+	Repository string
+	Env        map[string]string
+	Args       []string
+	// Deadline is the deadline of the command context, zero when it has none.
+	Deadline time.Time
+}
+
+// Fake answers restic commands of every repository. Respond returns the
+// stdout and error of a command; without it every command succeeds without
+// output. Stream commands get stdout split into lines.
+type Fake struct {
+	Respond func(call Call) (string, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Install makes restic.ForRepository and restic.ForCopy return runners of
+// fake until the test ends.
+func Install(t testing.TB, fake *Fake) *Fake {
+	t.Helper()
+
+	restic.SetFactory(func(repository config.Repository) restic.Runner {
+		return &runner{fake: fake, repository: repository}
+	})
+	t.Cleanup(func() {
+		restic.SetFactory(nil)
+	})
+
+	return fake
+}
+
+// Calls returns the commands run so far.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.calls)
+}
+
+func (f *Fake) run(ctx context.Context, repository config.Repository, args []string) (string, error) {
+	call := Call{Repository: repository.Name, Env: repository.Env, Args: slices.Clone(args)}
+	if deadline, ok := ctx.Deadline(); ok {
+		call.Deadline = deadline
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	if f.Respond == nil {
+		return "", nil
+	}
+	return f.Respond(call)
+}
+
+type runner struct {
+	fake       *Fake
+	repository config.Repository
+}
+
+func (r *runner) Run(ctx context.Context, stdout io.Writer, args ...string) error {
+	out, err := r.fake.run(ctx, r.repository, args)
+	if _, writeErr := io.WriteString(stdout, out); writeErr != nil {
+		return writeErr
+	}
+
+	return err
+}
+
+func (r *runner) Output(ctx context.Context, args ...string) (string, error) {
+	return r.fake.run(ctx, r.repository, args)
+}
+
+func (r *runner) Stream(ctx context.Context, args []string, handler func(json.RawMessage)) error {
+	out, err := r.fake.run(ctx, r.repository, args)
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			handler(json.RawMessage(line))
+		}
+	}
+
+	return err
+}
+
+func (r *runner) WithStderr(fn func(line string)) restic.Runner {
+	return r
+}
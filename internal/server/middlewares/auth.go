@@ -6,9 +6,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"prostic/internal/db/models"
+	usersservice "prostic/internal/service/users"
 	"prostic/internal/util"
 )
 
+const userKey = "user"
+
+// Auth checks the bearer token and whether the role of its user may call the
+// route. The user is loaded on every request so removing a user or changing
+// its role applies to tokens that were already issued.
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -23,11 +30,39 @@ func Auth() gin.HandlerFunc {
 			return
 		}
 
-		if err := util.ValidateJWT(token); err != nil {
+		claims, err := util.ValidateJWT(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		user, err := usersservice.Get(claims.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+			return
+		}
+		if user == nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
 
+		if !usersservice.Allows(user.Role, requiredRole(c.Request.Method, c.FullPath())) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+
+		c.Set(userKey, user)
 		c.Next()
 	}
 }
+
+// CurrentUser returns the user Auth loaded for the request.
+func CurrentUser(c *gin.Context) *models.User {
+	value, ok := c.Get(userKey)
+	if !ok {
+		return nil
+	}
+
+	user, _ := value.(*models.User)
+	return user
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	usersservice "prostic/internal/service/users"
+)
+
+// routeRoles lists the routes whose role differs from the default of their
+// method, keyed by method and route path.
+var routeRoles = map[string]string{
+	"POST /api/auth/change-password": usersservice.RoleViewer,
+	"GET /api/auth/me":               usersservice.RoleViewer,
+	"POST /api/repositories/init":    usersservice.RoleAdmin,
+	"GET /api/repositories/keys":     usersservice.RoleAdmin,
+	"POST /api/tasks/key-add":        usersservice.RoleAdmin,
+	"POST /api/tasks/key-remove":     usersservice.RoleAdmin,
+	"POST /api/tasks/key-rotate":     usersservice.RoleAdmin,
+}
+
+// adminPrefixes are route groups only admins may use.
+var adminPrefixes = []string{
+	"/api/users",
+}
+
+// requiredRole returns the least role that may call a route. Reading is
+// open to viewers, anything that changes state such as starting a backup or
+// deleting snapshots needs an operator.
+func requiredRole(method string, path string) string {
+	if role, ok := routeRoles[method+" "+path]; ok {
+		return role
+	}
+	for _, prefix := range adminPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return usersservice.RoleAdmin
+		}
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return usersservice.RoleViewer
+	default:
+		return usersservice.RoleOperator
+	}
+}
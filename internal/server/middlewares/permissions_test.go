@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+
+	usersservice "prostic/internal/service/users"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		admin    bool
+		operator bool
+		viewer   bool
+	}{
+		{name: "read", method: http.MethodGet, path: "/api/snapshots", admin: true, operator: true, viewer: true},
+		{name: "head", method: http.MethodHead, path: "/api/snapshots", admin: true, operator: true, viewer: true},
+		{name: "options", method: http.MethodOptions, path: "/api/snapshots", admin: true, operator: true, viewer: true},
+		{name: "start backup", method: http.MethodPost, path: "/api/backup/start", admin: true, operator: true},
+		{name: "update settings", method: http.MethodPut, path: "/api/backup/settings", admin: true, operator: true},
+		{name: "delete snapshot", method: http.MethodPost, path: "/api/tasks/delete-snapshot", admin: true, operator: true},
+		{name: "patch", method: http.MethodPatch, path: "/api/backup/settings", admin: true, operator: true},
+		{name: "delete", method: http.MethodDelete, path: "/api/snapshots/:id", admin: true, operator: true},
+		{name: "change own password", method: http.MethodPost, path: "/api/auth/change-password", admin: true, operator: true, viewer: true},
+		{name: "own account", method: http.MethodGet, path: "/api/auth/me", admin: true, operator: true, viewer: true},
+		{name: "init repository", method: http.MethodPost, path: "/api/repositories/init", admin: true},
+		{name: "list keys", method: http.MethodGet, path: "/api/repositories/keys", admin: true},
+		{name: "add key", method: http.MethodPost, path: "/api/tasks/key-add", admin: true},
+		{name: "remove key", method: http.MethodPost, path: "/api/tasks/key-remove", admin: true},
+		{name: "rotate key", method: http.MethodPost, path: "/api/tasks/key-rotate", admin: true},
+		{name: "list users", method: http.MethodGet, path: "/api/users", admin: true},
+		{name: "create user", method: http.MethodPost, path: "/api/users", admin: true},
+		{name: "update user", method: http.MethodPut, path: "/api/users/:id", admin: true},
+		{name: "delete user", method: http.MethodDelete, path: "/api/users/:id", admin: true},
+		{name: "admin prefix only matches whole segments", method: http.MethodGet, path: "/api/users-export", admin: true, operator: true, viewer: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			required := requiredRole(test.method, test.path)
+			allowed := map[string]bool{
+				usersservice.RoleAdmin:    test.admin,
+				usersservice.RoleOperator: test.operator,
+				usersservice.RoleViewer:   test.viewer,
+			}
+			for role, want := range allowed {
+				if got := usersservice.Allows(role, required); got != want {
+					t.Errorf("%s %s as %s: allowed = %v, want %v (requires %s)", test.method, test.path, role, got, want, required)
+				}
+			}
+		})
+	}
+}
@@ -6,8 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 
-	"prostic/internal/db/repo"
-	"prostic/internal/util"
+	"prostic/internal/server/middlewares"
+	usersservice "prostic/internal/service/users"
 )
 
 type changePasswordRequest struct {
@@ -25,13 +25,8 @@ func changePassword(c *gin.Context) {
 		return
 	}
 
-	passwordHash, err := util.HashPassword(request.Password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
-		return
-	}
-
-	if err := repo.UpdatePassword(passwordHash, false); err != nil {
+	user := middlewares.CurrentUser(c)
+	if err := usersservice.ChangePassword(user.ID, request.Password); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
 		return
 	}
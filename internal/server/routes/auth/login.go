@@ -1,16 +1,20 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	"prostic/internal/db/repo"
+	usersservice "prostic/internal/service/users"
 	"prostic/internal/util"
 )
 
+// loginRequest takes a username; clients from before user accounts only
+// send the password and log in as the admin user.
 type loginRequest struct {
+	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
@@ -25,22 +29,18 @@ func login(c *gin.Context) {
 		return
 	}
 
-	settings, err := repo.GetSettings()
+	user, err := usersservice.Authenticate(request.Username, request.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load settings"})
-		return
-	}
-	if settings == nil || settings.PasswordHash == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "password not configured"})
-		return
-	}
+		if errors.Is(err, usersservice.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
+			return
+		}
 
-	if err := util.CheckPassword(settings.PasswordHash, request.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid password"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
 		return
 	}
 
-	token, err := util.CreateJWT()
+	token, err := util.CreateJWT(user.ID, user.Username, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
 		return
@@ -48,6 +48,7 @@ func login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"token":               token,
-		"needsPasswordChange": settings.NeedsPasswordChange,
+		"user":                user,
+		"needsPasswordChange": user.NeedsPasswordChange,
 	})
 }
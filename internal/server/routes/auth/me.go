@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+// getMe returns the logged in user so clients can hide what its role may
+// not do.
+func getMe(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"user": middlewares.CurrentUser(c)})
+}
@@ -10,4 +10,5 @@ func InitAuthRouter(engine *gin.Engine) {
 	group := engine.Group("/api/auth")
 	group.POST("/login", login)
 	group.POST("/change-password", middlewares.Auth(), changePassword)
+	group.GET("/me", middlewares.Auth(), getMe)
 }
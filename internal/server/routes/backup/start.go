@@ -3,6 +3,7 @@ package backup
 import (
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -10,9 +11,25 @@ import (
 	runnerservice "prostic/internal/service/runner"
 )
 
+type startBackupRequest struct {
+	Repository string `json:"repository"`
+}
+
 func startBackup(c *gin.Context) {
-	run, err := backupservice.StartBackup("manual")
+	var request startBackupRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	run, err := backupservice.StartBackup("manual", strings.TrimSpace(request.Repository))
 	if err != nil {
+		if errors.Is(err, backupservice.ErrUnknownRepository) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown repository"})
+			return
+		}
 		if errors.Is(err, runnerservice.ErrBusy) {
 			c.JSON(http.StatusConflict, gin.H{"error": "another job is already running"})
 			return
@@ -0,0 +1,25 @@
+package backups
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	backupsetservice "prostic/internal/service/backup_sets"
+)
+
+func getBackup(c *gin.Context) {
+	detail, err := backupsetservice.Get(c.Query("repository"), c.Param("backupID"))
+	if err != nil {
+		if errors.Is(err, backupsetservice.ErrBackupNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "backup not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load backup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
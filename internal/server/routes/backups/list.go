@@ -0,0 +1,30 @@
+package backups
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	backupsetservice "prostic/internal/service/backup_sets"
+)
+
+func listBackups(c *gin.Context) {
+	limit := 0
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	sets, err := backupsetservice.List(c.Query("repository"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load backups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backups": sets})
+}
@@ -0,0 +1,14 @@
+package backups
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+func InitBackupsRouter(engine *gin.Engine) {
+	group := engine.Group("/api/backups")
+	group.Use(middlewares.Auth())
+	group.GET("", listBackups)
+	group.GET("/:backupID", getBackup)
+}
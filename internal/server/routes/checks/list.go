@@ -0,0 +1,19 @@
+package checks
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	checkservice "prostic/internal/service/check"
+)
+
+func listChecks(c *gin.Context) {
+	checks, err := checkservice.ListChecks(c.Query("repository"), 50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load checks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"checks": checks})
+}
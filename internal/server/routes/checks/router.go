@@ -0,0 +1,13 @@
+package checks
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+func InitChecksRouter(engine *gin.Engine) {
+	group := engine.Group("/api/checks")
+	group.Use(middlewares.Auth())
+	group.GET("", listChecks)
+}
@@ -15,6 +15,7 @@ type vmResponse struct {
 	IsVM       bool     `json:"isVM"`
 	ConfigFile string   `json:"configFile"`
 	Disks      []string `json:"disks"`
+	Repository string   `json:"repository"`
 }
 
 type configResponse struct {
@@ -43,6 +44,7 @@ func getConfig(c *gin.Context) {
 			IsVM:       vm.IsVM,
 			ConfigFile: appconfig.ConfigFilePath(vm),
 			Disks:      disks,
+			Repository: appconfig.TargetRepository(vm, ""),
 		})
 	}
 
@@ -0,0 +1,16 @@
+package events
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+// InitEventsRouter serves live progress as server-sent events. Clients send
+// the Authorization header like for every other API call, so the stream has
+// to be read with fetch instead of EventSource.
+func InitEventsRouter(engine *gin.Engine) {
+	group := engine.Group("/api/events")
+	group.Use(middlewares.Auth())
+	group.GET("", streamEvents)
+}
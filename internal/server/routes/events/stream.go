@@ -0,0 +1,73 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	liveservice "prostic/internal/service/live"
+)
+
+// keepAliveInterval keeps proxies from closing an idle stream.
+const keepAliveInterval = 15 * time.Second
+
+func streamEvents(c *gin.Context) {
+	messages, unsubscribe := liveservice.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, message := range liveservice.Snapshot() {
+		if err := writeEvent(c, message); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			if err := writeEvent(c, message); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeEvent writes message in the text/event-stream format. Replayed state
+// has no ID since it is not part of the sequence.
+func writeEvent(c *gin.Context, message liveservice.Message) error {
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return err
+	}
+
+	if message.ID != 0 {
+		if _, err := fmt.Fprintf(c.Writer, "id: %d\n", message.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", message.Type, data)
+	return err
+}
@@ -0,0 +1,23 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	healthservice "prostic/internal/service/health"
+)
+
+func getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": healthservice.StatusOK})
+}
+
+func getReady(c *gin.Context) {
+	report := healthservice.Ready(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != healthservice.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
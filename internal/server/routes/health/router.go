@@ -0,0 +1,12 @@
+package health
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// InitHealthRouter registers the probes for load balancers and uptime
+// monitors. They need no login.
+func InitHealthRouter(engine *gin.Engine) {
+	engine.GET("/healthz", getHealth)
+	engine.GET("/readyz", getReady)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	metricsservice "prostic/internal/service/metrics"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func getMetrics(c *gin.Context) {
+	body, err := metricsservice.Render()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to collect metrics\n")
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, []byte(body))
+}
+
+// requireToken checks the bearer token of metrics.token. Without a token the
+// metrics are only served when metrics.public is set.
+func requireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := appconfig.Get()
+		if cfg == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "metrics are not configured"})
+			return
+		}
+		if cfg.Metrics.Token == "" {
+			if !cfg.Metrics.Public {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "metrics.token is not configured"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Metrics.Token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,11 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// InitMetricsRouter serves /metrics outside of /api so Prometheus can scrape
+// it with the static token from the metrics config instead of a login.
+func InitMetricsRouter(engine *gin.Engine) {
+	engine.GET("/metrics", requireToken(), getMetrics)
+}
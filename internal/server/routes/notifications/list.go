@@ -0,0 +1,34 @@
+package notifications
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	notificationservice "prostic/internal/service/notifications"
+)
+
+func listChannels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"channels": notificationservice.ListChannels()})
+}
+
+func listDeliveries(c *gin.Context) {
+	limit := 100
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := notificationservice.ListDeliveries(c.Query("channel"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
@@ -0,0 +1,15 @@
+package notifications
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+func InitNotificationsRouter(engine *gin.Engine) {
+	group := engine.Group("/api/notifications")
+	group.Use(middlewares.Auth())
+	group.GET("", listChannels)
+	group.GET("/deliveries", listDeliveries)
+	group.POST("/test", testChannel)
+}
@@ -0,0 +1,37 @@
+package notifications
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	notificationservice "prostic/internal/service/notifications"
+)
+
+type testRequest struct {
+	Channel string `json:"channel"`
+}
+
+func testChannel(c *gin.Context) {
+	var request testRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(request.Channel) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "channel is required"})
+		return
+	}
+
+	delivery, err := notificationservice.Test(strings.TrimSpace(request.Channel))
+	if errors.Is(err, notificationservice.ErrUnknownChannel) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown notification channel"})
+		return
+	}
+
+	// a failed delivery is still a valid result of the test
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
@@ -6,7 +6,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	appconfig "prostic/internal/config"
 	"prostic/internal/db/repo"
+	checkservice "prostic/internal/service/check"
+	replicationservice "prostic/internal/service/replication"
+	slaservice "prostic/internal/service/sla"
 )
 
 type overviewHistoryPoint struct {
@@ -16,48 +20,77 @@ type overviewHistoryPoint struct {
 }
 
 type overviewResponse struct {
-	TotalSnapshots         int64                  `json:"totalSnapshots"`
-	TotalBackups           int64                  `json:"totalBackups"`
-	TotalVMs               int64                  `json:"totalVMs"`
-	DiskSnapshots          int64                  `json:"diskSnapshots"`
-	ConfigSnapshots        int64                  `json:"configSnapshots"`
-	LatestSnapshot         *time.Time             `json:"latestSnapshot"`
-	TotalSize              int64                  `json:"totalSize"`
-	TotalUncompressedSize  int64                  `json:"totalUncompressedSize"`
-	CompressionRatio       float64                `json:"compressionRatio"`
-	CompressionSpaceSaving float64                `json:"compressionSpaceSaving"`
-	TotalBlobCount         int64                  `json:"totalBlobCount"`
-	RepoSnapshotsCount     int64                  `json:"repoSnapshotsCount"`
-	LastRefreshedAt        *time.Time             `json:"lastRefreshedAt"`
-	History                []overviewHistoryPoint `json:"history"`
+	Repository             string                            `json:"repository"`
+	TotalSnapshots         int64                             `json:"totalSnapshots"`
+	TotalBackups           int64                             `json:"totalBackups"`
+	TotalVMs               int64                             `json:"totalVMs"`
+	DiskSnapshots          int64                             `json:"diskSnapshots"`
+	ConfigSnapshots        int64                             `json:"configSnapshots"`
+	LatestSnapshot         *time.Time                        `json:"latestSnapshot"`
+	TotalSize              int64                             `json:"totalSize"`
+	TotalUncompressedSize  int64                             `json:"totalUncompressedSize"`
+	CompressionRatio       float64                           `json:"compressionRatio"`
+	CompressionSpaceSaving float64                           `json:"compressionSpaceSaving"`
+	TotalBlobCount         int64                             `json:"totalBlobCount"`
+	RepoSnapshotsCount     int64                             `json:"repoSnapshotsCount"`
+	LastRefreshedAt        *time.Time                        `json:"lastRefreshedAt"`
+	History                []overviewHistoryPoint            `json:"history"`
+	Replication            []replicationservice.TargetStatus `json:"replication"`
+	LastCheckStatus        string                            `json:"lastCheckStatus"`
+	LastCheckAt            *time.Time                        `json:"lastCheckAt"`
+	LastCheckAgeSeconds    int64                             `json:"lastCheckAgeSeconds"`
+	SLABreaches            []slaservice.Alert                `json:"slaBreaches"`
 }
 
 func getOverview(c *gin.Context) {
-	overview, err := repo.GetSnapshotOverview()
+	repository := c.Query("repository")
+	if repository == "" {
+		repository = appconfig.DefaultRepository()
+	}
+
+	overview, err := repo.GetSnapshotOverview(repository)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load overview"})
+		return
+	}
+
+	repoStat, err := repo.GetLatestRepoStat(repository)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load overview"})
+		return
+	}
+
+	history, err := repo.ListRepoStats(repository, 60)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load overview"})
 		return
 	}
 
-	repoStat, err := repo.GetLatestRepoStat()
+	replication, err := replicationservice.GetStatus()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load overview"})
 		return
 	}
 
-	history, err := repo.ListRepoStats(60)
+	lastCheck, err := checkservice.GetSummary(repository)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load overview"})
 		return
 	}
 
 	response := overviewResponse{
-		TotalSnapshots:  overview.TotalSnapshots,
-		TotalBackups:    overview.TotalBackups,
-		TotalVMs:        overview.TotalVMs,
-		DiskSnapshots:   overview.DiskSnapshots,
-		ConfigSnapshots: overview.Configs,
-		LatestSnapshot:  overview.LatestSnapshot,
+		Repository:          repository,
+		TotalSnapshots:      overview.TotalSnapshots,
+		TotalBackups:        overview.TotalBackups,
+		TotalVMs:            overview.TotalVMs,
+		DiskSnapshots:       overview.DiskSnapshots,
+		ConfigSnapshots:     overview.Configs,
+		LatestSnapshot:      overview.LatestSnapshot,
+		Replication:         replication,
+		LastCheckStatus:     lastCheck.Status,
+		LastCheckAt:         lastCheck.CheckedAt,
+		LastCheckAgeSeconds: lastCheck.AgeSeconds,
+		SLABreaches:         slaservice.Breaches(),
 	}
 
 	if repoStat != nil {
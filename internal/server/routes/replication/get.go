@@ -0,0 +1,28 @@
+package replication
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	replicationservice "prostic/internal/service/replication"
+)
+
+func getReplication(c *gin.Context) {
+	targets, err := replicationservice.GetStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load replication status"})
+		return
+	}
+
+	replications, err := replicationservice.ListReplications(100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load replication status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"targets":      targets,
+		"replications": replications,
+	})
+}
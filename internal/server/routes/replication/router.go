@@ -0,0 +1,13 @@
+package replication
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+func InitReplicationRouter(engine *gin.Engine) {
+	group := engine.Group("/api/replication")
+	group.Use(middlewares.Auth())
+	group.GET("", getReplication)
+}
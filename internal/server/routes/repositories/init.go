@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	repositoryservice "prostic/internal/service/repository"
+)
+
+func initRepository(c *gin.Context) {
+	var request repositoryservice.InitOptions
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	output, err := repositoryservice.Init(c.Request.Context(), request)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositoryservice.ErrUnknownRepository):
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+		case errors.Is(err, repositoryservice.ErrAlreadyInitialized):
+			c.JSON(http.StatusConflict, gin.H{"error": "repository is already initialized"})
+		case errors.Is(err, repositoryservice.ErrInvalidCompression), errors.Is(err, repositoryservice.ErrInvalidConfig):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to initialize repository", "output": output})
+		}
+		return
+	}
+
+	status, err := repositoryservice.GetStatus(c.Request.Context(), request.Repository)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"output": output,
+	})
+}
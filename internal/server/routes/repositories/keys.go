@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	keysservice "prostic/internal/service/keys"
+)
+
+func listKeys(c *gin.Context) {
+	repository := c.Query("repository")
+	if repository == "" {
+		repository = appconfig.DefaultRepository()
+	}
+
+	keys, err := keysservice.List(c.Request.Context(), repository)
+	if err != nil {
+		if errors.Is(err, keysservice.ErrUnknownRepository) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repository": repository,
+		"keys":       keys,
+	})
+}
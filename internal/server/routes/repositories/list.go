@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/repo"
+)
+
+type repositoryResponse struct {
+	Name            string     `json:"name"`
+	Kind            string     `json:"kind"`
+	IsDefault       bool       `json:"isDefault"`
+	TotalSize       int64      `json:"totalSize"`
+	SnapshotsCount  int64      `json:"snapshotsCount"`
+	LastRefreshedAt *time.Time `json:"lastRefreshedAt"`
+}
+
+func listRepositories(c *gin.Context) {
+	cfg := appconfig.Get()
+	if cfg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "config not loaded"})
+		return
+	}
+
+	defaultRepository := appconfig.DefaultRepository()
+	response := make([]repositoryResponse, 0, len(cfg.Repositories))
+	for _, repository := range cfg.Repositories {
+		repoStat, err := repo.GetLatestRepoStat(repository.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load repositories"})
+			return
+		}
+
+		entry := repositoryResponse{
+			Name:      repository.Name,
+			Kind:      repository.Kind(),
+			IsDefault: repository.Name == defaultRepository,
+		}
+		if repoStat != nil {
+			entry.TotalSize = repoStat.TotalSize
+			entry.SnapshotsCount = repoStat.SnapshotsCount
+			entry.LastRefreshedAt = &repoStat.LastRefreshedAt
+		}
+		response = append(response, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"repositories": response})
+}
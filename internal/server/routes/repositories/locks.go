@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	locksservice "prostic/internal/service/locks"
+)
+
+func listLocks(c *gin.Context) {
+	repository := c.Query("repository")
+	if repository == "" {
+		repository = appconfig.DefaultRepository()
+	}
+
+	locks, err := locksservice.List(c.Request.Context(), repository)
+	if err != nil {
+		if errors.Is(err, locksservice.ErrUnknownRepository) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load locks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repository": repository,
+		"locks":      locks,
+	})
+}
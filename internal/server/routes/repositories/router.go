@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+// InitRepositoriesRouter serves the configured repositories. Routes below
+// the list take the repository as a query parameter and fall back to the
+// default repository.
+func InitRepositoriesRouter(engine *gin.Engine) {
+	group := engine.Group("/api/repositories")
+	group.Use(middlewares.Auth())
+	group.GET("", listRepositories)
+	group.GET("/status", getStatus)
+	group.POST("/init", initRepository)
+	group.GET("/locks", listLocks)
+	group.GET("/keys", listKeys)
+}
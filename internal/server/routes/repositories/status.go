@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	repositoryservice "prostic/internal/service/repository"
+)
+
+func getStatus(c *gin.Context) {
+	status, err := repositoryservice.GetStatus(c.Request.Context(), c.Query("repository"))
+	if err != nil {
+		if errors.Is(err, repositoryservice.ErrUnknownRepository) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,39 @@
+package snapshots
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	snapshotservice "prostic/internal/service/snapshots"
+)
+
+func diffSnapshots(c *gin.Context) {
+	fromID, err := strconv.ParseUint(c.Query("from"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from snapshot id"})
+		return
+	}
+	toID, err := strconv.ParseUint(c.Query("to"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to snapshot id"})
+		return
+	}
+
+	result, err := snapshotservice.Diff(c.Request.Context(), uint(fromID), uint(toID))
+	if err != nil {
+		switch {
+		case errors.Is(err, snapshotservice.ErrSnapshotNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "snapshot not found"})
+		case errors.Is(err, snapshotservice.ErrNotComparable):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to diff snapshots"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,41 @@
+package snapshots
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	snapshotservice "prostic/internal/service/snapshots"
+)
+
+type detailResponse struct {
+	*snapshotservice.Detail
+	ExistsInConfig bool `json:"existsInConfig"`
+}
+
+func getSnapshot(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid snapshot id"})
+		return
+	}
+
+	detail, err := snapshotservice.GetDetail(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, snapshotservice.ErrSnapshotNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "snapshot not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load snapshot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, detailResponse{
+		Detail:         detail,
+		ExistsInConfig: appconfig.SnapshotExists(detail.SnapshotType, detail.VMID, detail.SrcFile),
+	})
+}
@@ -1,7 +1,10 @@
 package snapshots
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -10,25 +13,112 @@ import (
 	"prostic/internal/db/repo"
 )
 
+// maxPageSize caps the limit query parameter.
+const maxPageSize = 1000
+
 type snapshotResponse struct {
 	models.Snapshot
 	ExistsInConfig bool `json:"existsInConfig"`
 }
 
 func listSnapshots(c *gin.Context) {
-	snapshots, err := repo.ListSnapshots()
+	query, err := parseSnapshotQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := repo.QuerySnapshots(query)
 	if err != nil {
+		if errors.Is(err, repo.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load snapshots"})
 		return
 	}
 
-	response := make([]snapshotResponse, 0, len(snapshots))
-	for _, snapshot := range snapshots {
+	response := make([]snapshotResponse, 0, len(page.Snapshots))
+	for _, snapshot := range page.Snapshots {
 		response = append(response, snapshotResponse{
 			Snapshot:       snapshot,
 			ExistsInConfig: appconfig.SnapshotExists(snapshot.SnapshotType, snapshot.VMID, snapshot.SrcFile),
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"snapshots": response})
+	c.JSON(http.StatusOK, gin.H{
+		"snapshots":  response,
+		"total":      page.Total,
+		"nextCursor": page.NextCursor,
+	})
+}
+
+// parseSnapshotQuery reads the filters of the list endpoint. Without a limit
+// every matching snapshot is returned.
+func parseSnapshotQuery(c *gin.Context) (repo.SnapshotQuery, error) {
+	query := repo.SnapshotQuery{
+		Repository:   c.Query("repository"),
+		BackupID:     c.Query("backupID"),
+		SnapshotType: c.Query("type"),
+		Search:       c.Query("q"),
+		Sort:         c.Query("sort"),
+		Ascending:    c.Query("order") == "asc",
+		Cursor:       c.Query("cursor"),
+	}
+
+	if raw := c.Query("vmid"); raw != "" {
+		vmID, err := strconv.Atoi(raw)
+		if err != nil {
+			return query, errors.New("invalid vmid")
+		}
+		query.VMID = &vmID
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, errors.New("invalid " + bound.name + " time, expected RFC 3339")
+		}
+		*bound.target = &parsed
+	}
+
+	if raw := c.Query("existsInConfig"); raw != "" {
+		exists, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, errors.New("invalid existsInConfig")
+		}
+		query.ExistsInConfig = &exists
+		for _, item := range appconfig.ConfiguredItems() {
+			query.ConfigItems = append(query.ConfigItems, repo.SnapshotItem{
+				VMID:         item.VMID,
+				SnapshotType: item.SnapshotType,
+				SrcFile:      item.SrcFile,
+			})
+		}
+	}
+
+	if query.Sort != "" && !repo.IsValidSnapshotSort(query.Sort) {
+		return query, errors.New("invalid sort, expected time, name, type or backupID")
+	}
+	if order := c.Query("order"); order != "" && order != "asc" && order != "desc" {
+		return query, errors.New("invalid order, expected asc or desc")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return query, errors.New("invalid limit")
+		}
+		query.Limit = min(limit, maxPageSize)
+	}
+
+	return query, nil
 }
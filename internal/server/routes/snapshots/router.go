@@ -10,4 +10,6 @@ func InitSnapshotsRouter(engine *gin.Engine) {
 	group := engine.Group("/api/snapshots")
 	group.Use(middlewares.Auth())
 	group.GET("", listSnapshots)
+	group.GET("/diff", diffSnapshots)
+	group.GET("/:id", getSnapshot)
 }
@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	checkservice "prostic/internal/service/check"
+	taskservice "prostic/internal/service/tasks"
+)
+
+type checkRequest struct {
+	Repository string `json:"repository"`
+}
+
+func checkRepository(c *gin.Context) {
+	var request checkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	var repositories []string
+	if repository := strings.TrimSpace(request.Repository); repository != "" {
+		repositories = []string{repository}
+	}
+
+	task, err := checkservice.Start(repositories)
+	if err != nil {
+		if errors.Is(err, checkservice.ErrUnknownRepository) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+			return
+		}
+		if errors.Is(err, taskservice.ErrTaskRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "another task is already running"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run check task"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"task": task})
+}
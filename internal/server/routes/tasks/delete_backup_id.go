@@ -3,17 +3,20 @@ package tasks
 import (
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	appconfig "prostic/internal/config"
 	"prostic/internal/db/models"
 	pruneservice "prostic/internal/service/prune"
 	taskservice "prostic/internal/service/tasks"
 )
 
 type deleteBackupIDRequest struct {
-	BackupID  string                           `json:"backupID"`
-	Snapshots []pruneservice.SnapshotCandidate `json:"snapshots"`
+	Repository string                           `json:"repository"`
+	BackupID   string                           `json:"backupID"`
+	Snapshots  []pruneservice.SnapshotCandidate `json:"snapshots"`
 }
 
 func deleteBackupID(c *gin.Context) {
@@ -27,9 +30,18 @@ func deleteBackupID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "backupID is required"})
 		return
 	}
+	request.Repository = strings.TrimSpace(request.Repository)
+	if request.Repository == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "repository is required"})
+		return
+	}
+	if appconfig.FindRepository(request.Repository) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+		return
+	}
 
 	if c.Query("confirm") != "true" {
-		snapshots, err := pruneservice.PreviewBackupID(request.BackupID)
+		snapshots, err := pruneservice.PreviewBackupID(request.Repository, request.BackupID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare delete backup task"})
 			return
@@ -39,8 +51,15 @@ func deleteBackupID(c *gin.Context) {
 		return
 	}
 
-	task, err := taskservice.StartBackgroundTask(pruneservice.TaskPurposeDeleteBackupID, func(_ *models.Task) (string, error) {
-		return pruneservice.RunDeleteBackupID(request.Snapshots)
+	for _, snapshot := range request.Snapshots {
+		if snapshot.Repository != "" && snapshot.Repository != request.Repository {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "snapshot " + snapshot.SnapshotID + " is not in repository " + request.Repository})
+			return
+		}
+	}
+
+	task, err := taskservice.StartBackgroundTask(pruneservice.TaskPurposeDeleteBackupID, func(_ *models.Task, logs *taskservice.Log) error {
+		return pruneservice.RunDeleteBackupID(logs, request.Repository, request.Snapshots)
 	})
 	if err != nil {
 		if errors.Is(err, taskservice.ErrTaskRunning) {
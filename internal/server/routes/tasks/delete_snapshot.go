@@ -27,8 +27,8 @@ func deleteSnapshot(c *gin.Context) {
 		return
 	}
 
-	task, err := taskservice.StartBackgroundTask(pruneservice.TaskPurposeDeleteSnapshot, func(_ *models.Task) (string, error) {
-		return pruneservice.RunDeleteSnapshot(request.Snapshot)
+	task, err := taskservice.StartBackgroundTask(pruneservice.TaskPurposeDeleteSnapshot, func(_ *models.Task, logs *taskservice.Log) error {
+		return pruneservice.RunDeleteSnapshot(logs, request.Snapshot)
 	})
 	if err != nil {
 		if errors.Is(err, taskservice.ErrTaskRunning) {
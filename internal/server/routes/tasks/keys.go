@@ -0,0 +1,143 @@
+package tasks
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	keysservice "prostic/internal/service/keys"
+	taskservice "prostic/internal/service/tasks"
+)
+
+type removeKeyRequest struct {
+	Repository string `json:"repository"`
+	KeyID      string `json:"keyID"`
+}
+
+type rotateKeyRequest struct {
+	Repository string `json:"repository"`
+}
+
+func addKey(c *gin.Context) {
+	var request keysservice.AddOptions
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	request.Repository = resolveRepository(request.Repository)
+	if err := keysservice.ValidateAdd(request); err != nil {
+		respondKeyError(c, err)
+		return
+	}
+
+	startKeyTask(c, keysservice.TaskPurposeKeyAdd, func(logs *taskservice.Log) error {
+		return keysservice.RunAdd(logs, request)
+	})
+}
+
+func removeKey(c *gin.Context) {
+	var request removeKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	repository := resolveRepository(request.Repository)
+	keyID := strings.TrimSpace(request.KeyID)
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyID is required"})
+		return
+	}
+
+	key, err := keysservice.FindKey(c.Request.Context(), repository, keyID)
+	if err != nil {
+		respondKeyError(c, err)
+		return
+	}
+	if key.Current {
+		respondKeyError(c, keysservice.ErrCurrentKey)
+		return
+	}
+
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusOK, gin.H{"key": key})
+		return
+	}
+
+	startKeyTask(c, keysservice.TaskPurposeKeyRemove, func(logs *taskservice.Log) error {
+		return keysservice.RunRemove(logs, repository, key.ID)
+	})
+}
+
+func rotateKey(c *gin.Context) {
+	var request rotateKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	repository := resolveRepository(request.Repository)
+	passwordFile, err := keysservice.ValidateRotate(repository)
+	if err != nil {
+		respondKeyError(c, err)
+		return
+	}
+
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusOK, gin.H{
+			"repository":   repository,
+			"passwordFile": passwordFile,
+		})
+		return
+	}
+
+	startKeyTask(c, keysservice.TaskPurposeKeyRotate, func(logs *taskservice.Log) error {
+		return keysservice.RunRotate(logs, repository)
+	})
+}
+
+func startKeyTask(c *gin.Context, purpose string, run func(logs *taskservice.Log) error) {
+	task, err := taskservice.StartBackgroundTask(purpose, func(_ *models.Task, logs *taskservice.Log) error {
+		return run(logs)
+	})
+	if err != nil {
+		if errors.Is(err, taskservice.ErrTaskRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "another task is already running"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run key task"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"task": task})
+}
+
+func respondKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, keysservice.ErrUnknownRepository):
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+	case errors.Is(err, keysservice.ErrUnknownKey):
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown key"})
+	case errors.Is(err, keysservice.ErrCurrentKey), errors.Is(err, keysservice.ErrAmbiguousKey), errors.Is(err, keysservice.ErrEmptyPassword), errors.Is(err, keysservice.ErrRotateNotSupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load keys"})
+	}
+}
+
+func resolveRepository(repository string) string {
+	repository = strings.TrimSpace(repository)
+	if repository == "" {
+		return appconfig.DefaultRepository()
+	}
+
+	return repository
+}
@@ -0,0 +1,173 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/db/models"
+	liveservice "prostic/internal/service/live"
+	taskservice "prostic/internal/service/tasks"
+)
+
+const (
+	// keepAliveInterval keeps proxies from closing an idle stream.
+	keepAliveInterval = 15 * time.Second
+	// followPollInterval catches up when live events were missed.
+	followPollInterval = 2 * time.Second
+)
+
+// getTaskLogs returns the lines of a task after the line number in after.
+// With follow=true the lines are sent as server-sent events until the task
+// finishes; a client that reconnects passes Last-Event-ID to continue where
+// it stopped.
+func getTaskLogs(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+
+	afterValue := c.Query("after")
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		afterValue = lastEventID
+	}
+	after := 0
+	if afterValue != "" {
+		after, err = strconv.Atoi(afterValue)
+		if err != nil || after < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after"})
+			return
+		}
+	}
+
+	if c.Query("follow") == "true" {
+		followTaskLogs(c, uint(taskID), after)
+		return
+	}
+
+	task, lines, running, err := taskservice.GetLines(uint(taskID), after)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load task logs"})
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task": task, "lines": lines, "running": running})
+}
+
+func followTaskLogs(c *gin.Context, taskID uint, after int) {
+	// task_log events only wake the stream up, the lines themselves are read
+	// by number so a burst that overflows the subscription loses nothing
+	messages, unsubscribe := liveservice.Subscribe()
+	defer unsubscribe()
+
+	task, lines, running, err := taskservice.GetLines(taskID, after)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load task logs"})
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	poll := time.NewTicker(followPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	last := after
+	for {
+		for _, line := range lines {
+			if err := writeLine(c, line); err != nil {
+				return
+			}
+			last = line.Seq
+		}
+		if !running {
+			task.Logs = ""
+			_ = writeEnd(c, task)
+			c.Writer.Flush()
+			return
+		}
+		c.Writer.Flush()
+
+		if !waitForLines(c, &messages, taskID, poll, keepAlive) {
+			return
+		}
+		task, lines, running, err = taskservice.GetLines(taskID, last)
+		if err != nil || task == nil {
+			return
+		}
+	}
+}
+
+// waitForLines blocks until the task logged or finished, or the poll
+// interval passed. It returns false once the client is gone.
+func waitForLines(c *gin.Context, messages *<-chan liveservice.Message, taskID uint, poll *time.Ticker, keepAlive *time.Ticker) bool {
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case message, ok := <-*messages:
+			if !ok {
+				// dropped as a slow subscriber, polling continues
+				*messages = nil
+				continue
+			}
+			switch data := message.Data.(type) {
+			case liveservice.TaskLogLine:
+				if data.TaskID == taskID {
+					return true
+				}
+			case liveservice.TaskFinished:
+				if data.Task.ID == taskID {
+					return true
+				}
+			}
+		case <-poll.C:
+			return true
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return false
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeLine sends one log line with its number as the event ID.
+func writeLine(c *gin.Context, line taskservice.LogLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: line\ndata: %s\n\n", line.Seq, data)
+	return err
+}
+
+// writeEnd sends the finished task and ends the stream.
+func writeEnd(c *gin.Context, task *models.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Writer, "event: end\ndata: %s\n\n", data)
+	return err
+}
@@ -17,7 +17,7 @@ type pruneRequest struct {
 
 func pruneNotInConfig(c *gin.Context) {
 	if c.Query("confirm") != "true" {
-		snapshots, err := pruneservice.PreviewNotInConfig()
+		snapshots, err := pruneservice.PreviewNotInConfig(c.Query("repository"))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare prune task"})
 			return
@@ -33,8 +33,8 @@ func pruneNotInConfig(c *gin.Context) {
 		return
 	}
 
-	task, err := taskservice.StartBackgroundTask(pruneservice.TaskPurposePruneNotInConfig, func(_ *models.Task) (string, error) {
-		return pruneservice.RunNotInConfig(request.Snapshots)
+	task, err := taskservice.StartBackgroundTask(pruneservice.TaskPurposePruneNotInConfig, func(_ *models.Task, logs *taskservice.Log) error {
+		return pruneservice.RunNotInConfig(logs, request.Snapshots)
 	})
 	if err != nil {
 		if errors.Is(err, taskservice.ErrTaskRunning) {
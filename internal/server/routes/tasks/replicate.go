@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	replicationservice "prostic/internal/service/replication"
+	taskservice "prostic/internal/service/tasks"
+)
+
+type replicateRequest struct {
+	Name     string `json:"name"`
+	BackupID string `json:"backupID"`
+}
+
+func replicate(c *gin.Context) {
+	var request replicateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(request.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	task, err := replicationservice.Start(strings.TrimSpace(request.Name), strings.TrimSpace(request.BackupID))
+	if err != nil {
+		if errors.Is(err, replicationservice.ErrUnknownReplication) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown replication"})
+			return
+		}
+		if errors.Is(err, taskservice.ErrTaskRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "another task is already running"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run replication task"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"task": task})
+}
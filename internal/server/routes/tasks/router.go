@@ -11,7 +11,14 @@ func InitTasksRouter(engine *gin.Engine) {
 	group.Use(middlewares.Auth())
 	group.GET("", listTasks)
 	group.GET("/status", getStatus)
+	group.GET("/:id/logs", getTaskLogs)
 	group.POST("/delete-snapshot", deleteSnapshot)
 	group.POST("/delete-backup-id", deleteBackupID)
 	group.POST("/prune-not-in-config", pruneNotInConfig)
+	group.POST("/replicate", replicate)
+	group.POST("/check", checkRepository)
+	group.POST("/unlock", unlock)
+	group.POST("/key-add", addKey)
+	group.POST("/key-remove", removeKey)
+	group.POST("/key-rotate", rotateKey)
 }
@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	locksservice "prostic/internal/service/locks"
+	taskservice "prostic/internal/service/tasks"
+)
+
+type unlockRequest struct {
+	Repository string `json:"repository"`
+	RemoveAll  bool   `json:"removeAll"`
+}
+
+func unlock(c *gin.Context) {
+	var request unlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	repository := strings.TrimSpace(request.Repository)
+	if repository == "" {
+		repository = appconfig.DefaultRepository()
+	}
+	if appconfig.FindRepository(repository) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown repository"})
+		return
+	}
+
+	if c.Query("confirm") != "true" {
+		locks, err := locksservice.List(c.Request.Context(), repository)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare unlock task"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"locks": locks})
+		return
+	}
+
+	task, err := taskservice.StartBackgroundTask(locksservice.TaskPurposeUnlock, func(_ *models.Task, logs *taskservice.Log) error {
+		return locksservice.RunUnlock(logs, repository, request.RemoveAll)
+	})
+	if err != nil {
+		if errors.Is(err, taskservice.ErrTaskRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "another task is already running"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to run unlock task"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"task": task})
+}
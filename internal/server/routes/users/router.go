@@ -0,0 +1,17 @@
+package users
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+// InitUsersRouter serves user management. Auth limits the group to admins.
+func InitUsersRouter(engine *gin.Engine) {
+	group := engine.Group("/api/users")
+	group.Use(middlewares.Auth())
+	group.GET("", listUsers)
+	group.POST("", createUser)
+	group.PUT("/:id", updateUser)
+	group.DELETE("/:id", deleteUser)
+}
@@ -0,0 +1,101 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	usersservice "prostic/internal/service/users"
+)
+
+type createUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+func listUsers(c *gin.Context) {
+	users, err := usersservice.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func createUser(c *gin.Context) {
+	var request createUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	user, err := usersservice.Create(request.Username, request.Password, request.Role)
+	if err != nil {
+		respondUserError(c, err, "failed to create user")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"user": user})
+}
+
+func updateUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request usersservice.Update
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	user, err := usersservice.Modify(userID, request)
+	if err != nil {
+		respondUserError(c, err, "failed to update user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func deleteUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := usersservice.Delete(userID); err != nil {
+		respondUserError(c, err, "failed to delete user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseUserID(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+
+	return uint(userID), true
+}
+
+func respondUserError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, usersservice.ErrUnknownUser):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usersservice.ErrUserExists), errors.Is(err, usersservice.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usersservice.ErrInvalidUsername), errors.Is(err, usersservice.ErrInvalidRole), errors.Is(err, usersservice.ErrEmptyPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
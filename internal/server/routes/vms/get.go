@@ -0,0 +1,32 @@
+package vms
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	coverageservice "prostic/internal/service/coverage"
+)
+
+func getVM(c *gin.Context) {
+	vmID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vm id"})
+		return
+	}
+
+	guest, err := coverageservice.Get(vmID)
+	if err != nil {
+		if errors.Is(err, coverageservice.ErrUnknownVM) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown vm"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load vm"})
+		return
+	}
+
+	c.JSON(http.StatusOK, guest)
+}
@@ -0,0 +1,36 @@
+package vms
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	backupservice "prostic/internal/service/backups"
+)
+
+func getHistory(c *gin.Context) {
+	vmID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vm id"})
+		return
+	}
+
+	limit := 100
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	items, err := backupservice.ListVMHistory(vmID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load vm history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
@@ -0,0 +1,19 @@
+package vms
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	coverageservice "prostic/internal/service/coverage"
+)
+
+func listVMs(c *gin.Context) {
+	guests, err := coverageservice.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load vms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vms": guests})
+}
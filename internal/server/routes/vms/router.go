@@ -0,0 +1,15 @@
+package vms
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"prostic/internal/server/middlewares"
+)
+
+func InitVMsRouter(engine *gin.Engine) {
+	group := engine.Group("/api/vms")
+	group.Use(middlewares.Auth())
+	group.GET("", listVMs)
+	group.GET("/:id", getVM)
+	group.GET("/:id/history", getHistory)
+}
@@ -17,28 +17,65 @@ import (
 	embedded "prostic/internal/embed"
 	authroutes "prostic/internal/server/routes/auth"
 	backuproutes "prostic/internal/server/routes/backup"
+	backupsroutes "prostic/internal/server/routes/backups"
+	checkroutes "prostic/internal/server/routes/checks"
 	configroutes "prostic/internal/server/routes/config"
+	eventroutes "prostic/internal/server/routes/events"
+	healthroutes "prostic/internal/server/routes/health"
+	metricsroutes "prostic/internal/server/routes/metrics"
+	notificationroutes "prostic/internal/server/routes/notifications"
 	overviewroutes "prostic/internal/server/routes/overview"
 	refreshroutes "prostic/internal/server/routes/refresh"
+	replicationroutes "prostic/internal/server/routes/replication"
+	repositoryroutes "prostic/internal/server/routes/repositories"
 	snapshotroutes "prostic/internal/server/routes/snapshots"
 	taskroutes "prostic/internal/server/routes/tasks"
+	userroutes "prostic/internal/server/routes/users"
+	vmroutes "prostic/internal/server/routes/vms"
 	backupservice "prostic/internal/service/backups"
+	checkservice "prostic/internal/service/check"
+	healthservice "prostic/internal/service/health"
+	liveservice "prostic/internal/service/live"
+	notificationservice "prostic/internal/service/notifications"
+	replicationservice "prostic/internal/service/replication"
+	slaservice "prostic/internal/service/sla"
+	taskservice "prostic/internal/service/tasks"
 )
 
 func Start(addr string) error {
 	if _, err := db.Get(); err != nil {
 		return err
 	}
+	if err := taskservice.RecoverInterrupted(); err != nil {
+		return err
+	}
+	if err := backupservice.RecoverInterrupted(); err != nil {
+		return err
+	}
 
 	engine := gin.Default()
 	authroutes.InitAuthRouter(engine)
 	backuproutes.InitBackupRouter(engine)
+	backupsroutes.InitBackupsRouter(engine)
+	checkroutes.InitChecksRouter(engine)
 	configroutes.InitConfigRouter(engine)
+	eventroutes.InitEventsRouter(engine)
+	healthroutes.InitHealthRouter(engine)
+	metricsroutes.InitMetricsRouter(engine)
+	notificationroutes.InitNotificationsRouter(engine)
 	overviewroutes.InitOverviewRouter(engine)
 	refreshroutes.InitRefreshRouter(engine)
+	replicationroutes.InitReplicationRouter(engine)
+	repositoryroutes.InitRepositoriesRouter(engine)
 	snapshotroutes.InitSnapshotsRouter(engine)
 	taskroutes.InitTasksRouter(engine)
+	userroutes.InitUsersRouter(engine)
+	vmroutes.InitVMsRouter(engine)
 	registerStaticRoutes(engine)
+	backupservice.OnRunFinished(replicationservice.AfterBackup)
+	backupservice.OnRunFinished(slaservice.AfterBackup)
+	notificationservice.Start()
+	liveservice.Start()
 	startSchedulers()
 
 	return engine.Run(addr)
@@ -50,7 +87,13 @@ func startSchedulers() {
 		defer ticker.Stop()
 
 		for {
-			backupservice.SchedulerTick(time.Now().In(time.Local))
+			now := time.Now().In(time.Local)
+			healthservice.SchedulerHeartbeat(now)
+			backupservice.SchedulerTick(now)
+			replicationservice.SchedulerTick(now)
+			checkservice.SchedulerTick(now)
+			slaservice.SchedulerTick(now)
+			notificationservice.SchedulerTick(now)
 			<-ticker.C
 		}
 	}()
@@ -0,0 +1,19 @@
+package backupsets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "prostic-backup-sets-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("PROSTIC_DB_PATH", filepath.Join(dir, "test.db"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
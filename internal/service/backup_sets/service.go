@@ -0,0 +1,216 @@
+package backupsets
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+)
+
+const (
+	ExpectedFromRun    = "run"
+	ExpectedFromConfig = "config"
+)
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+type Set struct {
+	repo.BackupSetSummary
+	// ExpectedItems is the number of items the run planned for the
+	// repository, or the number of configured items when the run is not
+	// known.
+	ExpectedItems  int               `json:"expectedItems"`
+	ExpectedSource string            `json:"expectedSource"`
+	Complete       bool              `json:"complete"`
+	BackupRun      *models.BackupRun `json:"backupRun"`
+}
+
+type Guest struct {
+	VMID      *int              `json:"vmid"`
+	Name      string            `json:"name"`
+	VMType    string            `json:"vmType"`
+	Snapshots []models.Snapshot `json:"snapshots"`
+}
+
+type Detail struct {
+	Set
+	Guests []Guest `json:"guests"`
+	// Missing lists the items the run planned for the repository, or the
+	// configured items when the run is not known, that have no snapshot in
+	// this backup. The backup is complete when it is empty.
+	Missing []appconfig.ConfiguredItem `json:"missing"`
+}
+
+func List(repository string, limit int) ([]Set, error) {
+	summaries, err := repo.ListBackupSets(repository, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	backupIDs := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		backupIDs = append(backupIDs, summary.BackupID)
+	}
+	runs, err := repo.ListBackupRunsByBackupID(backupIDs)
+	if err != nil {
+		return nil, err
+	}
+	items, err := repo.ListBackupSetItems(repository, backupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]Set, 0, len(summaries))
+	for _, summary := range summaries {
+		var run *models.BackupRun
+		if found, ok := runs[summary.BackupID]; ok {
+			run = &found
+		}
+		set, _ := newSet(summary, run, repository, items[summary.BackupID])
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
+
+func Get(repository string, backupID string) (*Detail, error) {
+	summary, err := repo.GetBackupSet(repository, backupID)
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil {
+		return nil, ErrBackupNotFound
+	}
+
+	run, err := repo.FindBackupRunByBackupID(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := repo.ListBackupSetSnapshots(repository, backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	set, missing := newSet(*summary, run, repository, snapshots)
+	detail := &Detail{
+		Set:     set,
+		Guests:  groupGuests(snapshots),
+		Missing: missing,
+	}
+
+	return detail, nil
+}
+
+// newSet decides whether a backup is complete in repository, or in all
+// repositories when it is empty, and returns the items it lacks. Items come
+// from the run when it recorded them, otherwise from the config.
+func newSet(summary repo.BackupSetSummary, run *models.BackupRun, repository string, snapshots []models.Snapshot) (Set, []appconfig.ConfiguredItem) {
+	set := Set{
+		BackupSetSummary: summary,
+		ExpectedSource:   ExpectedFromConfig,
+		BackupRun:        run,
+	}
+
+	expected := appconfig.ConfiguredItems()
+	if planned, ok := plannedItems(run, repository); ok {
+		expected = planned
+		set.ExpectedSource = ExpectedFromRun
+	}
+	missing := missingItems(expected, snapshots)
+	set.ExpectedItems = len(expected)
+	set.Complete = len(missing) == 0
+
+	// runs from before planned items were recorded only know their count
+	if set.ExpectedSource == ExpectedFromConfig && run != nil && run.TotalItems > 0 {
+		set.ExpectedItems = run.TotalItems
+		set.ExpectedSource = ExpectedFromRun
+		set.Complete = summary.ItemCount >= run.TotalItems
+	}
+
+	return set, missing
+}
+
+// plannedItems returns the items run set out to back up that belong in
+// repository: those backed up to it and those of sources replicated into it.
+// ok is false for runs that did not record their items.
+func plannedItems(run *models.BackupRun, repository string) (items []appconfig.ConfiguredItem, ok bool) {
+	if run == nil || run.PlannedItems == "" {
+		return nil, false
+	}
+
+	var planned []appconfig.PlannedItem
+	if err := json.Unmarshal([]byte(run.PlannedItems), &planned); err != nil {
+		return nil, false
+	}
+
+	sources := sourceRepositories(repository)
+	items = make([]appconfig.ConfiguredItem, 0, len(planned))
+	for _, item := range planned {
+		if repository == "" || item.Repository == "" || sources[item.Repository] {
+			items = append(items, item.ConfiguredItem)
+		}
+	}
+
+	return items, true
+}
+
+// sourceRepositories returns repository and every repository replicated
+// into it.
+func sourceRepositories(repository string) map[string]bool {
+	sources := map[string]bool{repository: true}
+	if appconfig.Get() == nil {
+		return sources
+	}
+
+	for _, replication := range appconfig.Get().Replications {
+		if slices.Contains(replication.Targets, repository) {
+			sources[replication.Source] = true
+		}
+	}
+
+	return sources
+}
+
+func groupGuests(snapshots []models.Snapshot) []Guest {
+	guests := make([]Guest, 0)
+	index := make(map[int]int)
+	for _, snapshot := range snapshots {
+		if snapshot.VMID == nil {
+			guests = append(guests, Guest{Name: snapshot.Name, VMType: snapshot.VMType, Snapshots: []models.Snapshot{snapshot}})
+			continue
+		}
+
+		i, ok := index[*snapshot.VMID]
+		if !ok {
+			i = len(guests)
+			index[*snapshot.VMID] = i
+			guests = append(guests, Guest{VMID: snapshot.VMID, Name: snapshot.Name, VMType: snapshot.VMType})
+		}
+		guests[i].Snapshots = append(guests[i].Snapshots, snapshot)
+	}
+
+	return guests
+}
+
+func missingItems(items []appconfig.ConfiguredItem, snapshots []models.Snapshot) []appconfig.ConfiguredItem {
+	present := make(map[appconfig.ConfiguredItem]bool, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.VMID == nil {
+			continue
+		}
+		present[appconfig.ConfiguredItem{VMID: *snapshot.VMID, SnapshotType: snapshot.SnapshotType, SrcFile: snapshot.SrcFile}] = true
+	}
+
+	missing := make([]appconfig.ConfiguredItem, 0)
+	for _, item := range items {
+		if !present[item] {
+			missing = append(missing, item)
+		}
+	}
+
+	return missing
+}
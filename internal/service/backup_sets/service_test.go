@@ -0,0 +1,159 @@
+package backupsets
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+)
+
+// Guest 100 is backed up to local, which is replicated to offsite, and guest
+// 200 has its own repository.
+const backupSetsConfig = `vms:
+  - name: web
+    id: 100
+    disks: [/dev/pve/vm-100-disk-0]
+  - name: db
+    id: 200
+    disks: [/dev/pve/vm-200-disk-0]
+    repository: archive
+repositories:
+  - name: local
+    env: {RESTIC_REPOSITORY: /srv/local, RESTIC_PASSWORD: secret}
+  - name: archive
+    env: {RESTIC_REPOSITORY: /srv/archive, RESTIC_PASSWORD: secret}
+  - name: offsite
+    env: {RESTIC_REPOSITORY: /srv/offsite, RESTIC_PASSWORD: secret}
+replication:
+  - name: offsite
+    source: local
+    targets: [offsite]
+`
+
+var (
+	webDisk = appconfig.ConfiguredItem{VMID: 100, SnapshotType: "disk", SrcFile: "/dev/pve/vm-100-disk-0"}
+	dbDisk  = appconfig.ConfiguredItem{VMID: 200, SnapshotType: "disk", SrcFile: "/dev/pve/vm-200-disk-0"}
+)
+
+func loadConfig(t *testing.T) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(backupSetsConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appconfig.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedBackup records a run with planned, or only with totalItems when
+// planned is nil like runs of older versions, and snapshots by repository.
+func seedBackup(t *testing.T, backupID string, planned []appconfig.PlannedItem, totalItems int, snapshots map[string][]appconfig.ConfiguredItem) {
+	t.Helper()
+
+	run := &models.BackupRun{Trigger: "schedule", Repository: "local", Status: "success", BackupID: backupID, TotalItems: totalItems, StartedAt: time.Now()}
+	if planned != nil {
+		data, err := json.Marshal(planned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.PlannedItems = string(data)
+	}
+	if err := repo.CreateBackupRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	for repository, items := range snapshots {
+		rows := make([]models.Snapshot, 0, len(items))
+		for _, item := range items {
+			vmID := item.VMID
+			rows = append(rows, models.Snapshot{
+				SnapshotID:   backupID + repository + item.SrcFile,
+				Time:         time.Now(),
+				BackupID:     backupID,
+				VMID:         &vmID,
+				SnapshotType: item.SnapshotType,
+				SrcFile:      item.SrcFile,
+			})
+		}
+		if _, err := repo.MergeSnapshots(repository, rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompleteFollowsPlannedItemsOfTheRepository(t *testing.T) {
+	loadConfig(t)
+	planned := []appconfig.PlannedItem{
+		{ConfiguredItem: webDisk, Repository: "local"},
+		{ConfiguredItem: dbDisk, Repository: "archive"},
+	}
+	seedBackup(t, "spread", planned, 2, map[string][]appconfig.ConfiguredItem{
+		"local":   {webDisk},
+		"archive": {dbDisk},
+	})
+	seedBackup(t, "partial", planned, 2, map[string][]appconfig.ConfiguredItem{
+		"local": {webDisk},
+	})
+	seedBackup(t, "legacy", nil, 1, map[string][]appconfig.ConfiguredItem{
+		"local": {webDisk},
+	})
+	// a replication target expects the items of its source
+	seedBackup(t, "spread-copy", planned, 2, map[string][]appconfig.ConfiguredItem{
+		"local":   {webDisk},
+		"offsite": {dbDisk},
+	})
+
+	tests := []struct {
+		name         string
+		repository   string
+		backupID     string
+		wantComplete bool
+		wantExpected int
+		wantMissing  []appconfig.ConfiguredItem
+		// runs without planned items list the configured items as missing
+		skipMissing bool
+	}{
+		{name: "first repository of a spread run", repository: "local", backupID: "spread", wantComplete: true, wantExpected: 1},
+		{name: "second repository of a spread run", repository: "archive", backupID: "spread", wantComplete: true, wantExpected: 1},
+		{name: "spread run in all repositories", backupID: "spread", wantComplete: true, wantExpected: 2},
+		{name: "partial run in the complete repository", repository: "local", backupID: "partial", wantComplete: true, wantExpected: 1},
+		{name: "partial run in all repositories", backupID: "partial", wantExpected: 2, wantMissing: []appconfig.ConfiguredItem{dbDisk}},
+		{name: "replicated copy not made yet", repository: "offsite", backupID: "spread-copy", wantExpected: 1, wantMissing: []appconfig.ConfiguredItem{webDisk}},
+		{name: "run without planned items", repository: "local", backupID: "legacy", wantExpected: 1, wantComplete: true, skipMissing: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			detail, err := Get(test.repository, test.backupID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if detail.Complete != test.wantComplete {
+				t.Errorf("complete = %v, want %v", detail.Complete, test.wantComplete)
+			}
+			if detail.ExpectedItems != test.wantExpected {
+				t.Errorf("expected items = %d, want %d", detail.ExpectedItems, test.wantExpected)
+			}
+			if !test.skipMissing && len(detail.Missing) != len(test.wantMissing) || (len(test.wantMissing) > 0 && detail.Missing[0] != test.wantMissing[0]) {
+				t.Errorf("missing = %v, want %v", detail.Missing, test.wantMissing)
+			}
+
+			sets, err := List(test.repository, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, set := range sets {
+				if set.BackupID == test.backupID && set.Complete != detail.Complete {
+					t.Errorf("list says complete = %v, detail says %v", set.Complete, detail.Complete)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package backups
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -9,38 +10,54 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"prostic/internal/config"
 	"prostic/internal/restic"
+	locksservice "prostic/internal/service/locks"
+	repositoryservice "prostic/internal/service/repository"
 )
 
 const charset = "0123456789abcdefghijklmnopqrstuvwxyz"
 
 func RunBackup() error {
-	return RunBackupWithObserver(consoleObserver{})
+	return RunBackupWithObserver(context.Background(), "", consoleObserver{})
 }
 
-func RunBackupWithObserver(observer Observer) error {
+// RunBackupWithObserver backs up every configured guest. repository is the
+// job target; guests with their own repository ignore it.
+func RunBackupWithObserver(ctx context.Context, repository string, observer Observer) error {
 	if config.Get() == nil {
 		return errors.New("no config provided")
 	}
-	if !isResticConfigCorrect() {
-		return errors.New("restic config invalid. Maybe you need to init the repo. Run restic init")
+	observer = normalizeObserver(observer)
+	for _, target := range targetRepositories(repository) {
+		if err := checkRepository(ctx, target); err != nil {
+			return err
+		}
+
+		err := locksservice.RemoveStaleLocks(ctx, target, config.Get().Backup.LockThreshold(), func(message string) {
+			observer.OnEvent(Event{Type: EventLog, Message: message})
+		})
+		if err != nil {
+			return fmt.Errorf("could not remove stale locks in %s: %v", target, err)
+		}
 	}
 
-	observer = normalizeObserver(observer)
 	backupID := randomID(10)
-	totalItems := countBackupItems()
+	plannedItems := plannedBackupItems(repository)
+	totalItems := len(plannedItems)
 	completedItems := 0
 	observer.OnEvent(Event{
-		Type:       EventRunStarted,
-		BackupID:   backupID,
-		TotalItems: totalItems,
+		Type:         EventRunStarted,
+		BackupID:     backupID,
+		TotalItems:   totalItems,
+		PlannedItems: plannedItems,
 	})
 
 	for _, vm := range config.Get().VMs {
-		if err := runVMBackup(vm, backupID, observer, totalItems, &completedItems); err != nil {
+		if err := runVMBackup(ctx, vm, config.TargetRepository(vm, repository), backupID, observer, totalItems, &completedItems); err != nil {
 			observer.OnEvent(Event{
 				Type:           EventRunFailed,
 				BackupID:       backupID,
@@ -61,7 +78,12 @@ func RunBackupWithObserver(observer Observer) error {
 	return nil
 }
 
-func runVMBackup(vm config.VM, backupID string, observer Observer, totalItems int, completedItems *int) error {
+func runVMBackup(ctx context.Context, vm config.VM, repository string, backupID string, observer Observer, totalItems int, completedItems *int) error {
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return err
+	}
+
 	vmPrefix := "lxc"
 	if vm.IsVM {
 		vmPrefix = "vm"
@@ -107,15 +129,17 @@ func runVMBackup(vm config.VM, backupID string, observer Observer, totalItems in
 			}
 		}
 
-		totalGB := probeLVSizeGB(disk)
-		if totalGB <= 0 {
-			totalGB = 5.0
+		size, err := deviceSize(snapPath)
+		if err != nil {
+			observer.OnEvent(Event{Type: EventLog, BackupID: backupID, Message: err.Error()})
 		}
 		item := &Item{
-			VM:       vm,
-			ItemType: "disk",
-			SrcFile:  disk,
-			DestFile: destFile,
+			VM:         vm,
+			ItemType:   "disk",
+			SrcFile:    disk,
+			DestFile:   destFile,
+			Repository: repository,
+			Size:       size,
 		}
 		observer.OnEvent(Event{
 			Type:           EventItemStarted,
@@ -123,7 +147,7 @@ func runVMBackup(vm config.VM, backupID string, observer Observer, totalItems in
 			TotalItems:     totalItems,
 			CompletedItems: *completedItems,
 			Item:           item,
-			BytesTotal:     int64(totalGB * 1024 * 1024 * 1024),
+			BytesTotal:     size,
 		})
 
 		args := []string{
@@ -143,38 +167,13 @@ func runVMBackup(vm config.VM, backupID string, observer Observer, totalItems in
 			"/bin/dd", "if=" + snapPath, "bs=4M",
 		}
 
-		err = restic.RunResticJSONStream(args, func(obj map[string]interface{}) {
-			handleResticMsg(obj, func(doneBytes int64, message string) {
-				if message != "" {
-					observer.OnEvent(Event{Type: EventLog, BackupID: backupID, Message: message})
-				}
-				if doneBytes >= 0 {
-					observer.OnEvent(Event{
-						Type:           EventItemProgress,
-						BackupID:       backupID,
-						TotalItems:     totalItems,
-						CompletedItems: *completedItems,
-						Item:           item,
-						BytesDone:      doneBytes,
-						BytesTotal:     int64(totalGB * 1024 * 1024 * 1024),
-					})
-				}
-			})
-		})
+		summary, err := runResticBackup(ctx, runner, args, backupID, item, observer, totalItems, *completedItems)
 		_ = removeSnapshot(snapPath)
 		if err != nil {
 			return fmt.Errorf("restic backup failed for %s: %v", snapPath, err)
 		}
 		(*completedItems)++
-		observer.OnEvent(Event{
-			Type:           EventItemDone,
-			BackupID:       backupID,
-			TotalItems:     totalItems,
-			CompletedItems: *completedItems,
-			Item:           item,
-			BytesDone:      int64(totalGB * 1024 * 1024 * 1024),
-			BytesTotal:     int64(totalGB * 1024 * 1024 * 1024),
-		})
+		observer.OnEvent(itemDoneEvent(backupID, item, totalItems, *completedItems, summary))
 	}
 
 	var srcConfig string
@@ -191,10 +190,12 @@ func runVMBackup(vm config.VM, backupID string, observer Observer, totalItems in
 			return err
 		}
 		item := &Item{
-			VM:       vm,
-			ItemType: "config",
-			SrcFile:  srcConfig,
-			DestFile: destConfig,
+			VM:         vm,
+			ItemType:   "config",
+			SrcFile:    srcConfig,
+			DestFile:   destConfig,
+			Repository: repository,
+			Size:       fileInfo.Size(),
 		}
 		observer.OnEvent(Event{
 			Type:           EventItemStarted,
@@ -220,37 +221,12 @@ func runVMBackup(vm config.VM, backupID string, observer Observer, totalItems in
 			"--",
 			"/bin/dd", "if=" + srcConfig, "bs=4M",
 		}
-		err = restic.RunResticJSONStream(args, func(obj map[string]interface{}) {
-			handleResticMsg(obj, func(doneBytes int64, message string) {
-				if message != "" {
-					observer.OnEvent(Event{Type: EventLog, BackupID: backupID, Message: message})
-				}
-				if doneBytes >= 0 {
-					observer.OnEvent(Event{
-						Type:           EventItemProgress,
-						BackupID:       backupID,
-						TotalItems:     totalItems,
-						CompletedItems: *completedItems,
-						Item:           item,
-						BytesDone:      doneBytes,
-						BytesTotal:     fileInfo.Size(),
-					})
-				}
-			})
-		})
+		summary, err := runResticBackup(ctx, runner, args, backupID, item, observer, totalItems, *completedItems)
 		if err != nil {
 			return fmt.Errorf("restic backup failed for config %s: %v", srcConfig, err)
 		}
 		(*completedItems)++
-		observer.OnEvent(Event{
-			Type:           EventItemDone,
-			BackupID:       backupID,
-			TotalItems:     totalItems,
-			CompletedItems: *completedItems,
-			Item:           item,
-			BytesDone:      fileInfo.Size(),
-			BytesTotal:     fileInfo.Size(),
-		})
+		observer.OnEvent(itemDoneEvent(backupID, item, totalItems, *completedItems, summary))
 	} else {
 		observer.OnEvent(Event{Type: EventLog, BackupID: backupID, Message: "Config file not found, skipping: " + srcConfig})
 	}
@@ -267,9 +243,37 @@ func removeSnapshot(path string) error {
 	return nil
 }
 
-func isResticConfigCorrect() bool {
-	err := restic.RunResticCommand(false, "snapshots")
-	return err == nil
+// checkRepository fails for repositories a backup cannot write to. Locks are
+// left for restic to judge since shared locks do not block a backup.
+func checkRepository(ctx context.Context, repository string) error {
+	status, err := repositoryservice.GetStatus(ctx, repository)
+	if err != nil {
+		return err
+	}
+
+	switch status.Status {
+	case restic.RepositoryOK, restic.RepositoryLocked:
+		return nil
+	case restic.RepositoryNotInitialized:
+		return fmt.Errorf("repository %s is not initialized. Run prostic repo init --repository %s", repository, repository)
+	}
+
+	return fmt.Errorf("repository %s is %s: %s", repository, status.Status, status.Message)
+}
+
+func targetRepositories(jobRepository string) []string {
+	seen := make(map[string]bool)
+	targets := make([]string, 0)
+	for _, vm := range config.Get().VMs {
+		target := config.TargetRepository(vm, jobRepository)
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+
+	return targets
 }
 
 func randomID(n int) string {
@@ -295,64 +299,142 @@ func isThinLV(lvPath string) (bool, error) {
 	return attr[0] == 'V', nil
 }
 
-func handleResticMsg(obj map[string]interface{}, report func(doneBytes int64, message string)) {
-	mt, _ := obj["message_type"].(string)
-	switch mt {
-	case "status", "progress", "snapshot":
-		var done int64
-		if v, ok := obj["bytes_done"]; ok {
-			switch vv := v.(type) {
-			case float64:
-				done = int64(vv)
-			case int64:
-				done = vv
-			case int:
-				done = int64(vv)
-			}
-		}
-		report(done, "")
-	case "error":
-		if message, ok := obj["message"].(string); ok {
-			report(-1, "RESTIC ERROR: "+message)
+func runResticBackup(ctx context.Context, runner restic.Runner, args []string, backupID string, item *Item, observer Observer, totalItems int, completedItems int) (*restic.SummaryMessage, error) {
+	// stderr lines arrive on a separate goroutine, so serialize all events
+	var mu sync.Mutex
+	emit := func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		observer.OnEvent(event)
+	}
+
+	logLine := func(line string) {
+		emit(Event{Type: EventLog, BackupID: backupID, Message: line})
+	}
+	runner = runner.WithStderr(func(line string) {
+		if isNoisyStderr(line) {
+			return
 		}
-	default:
-		if m, ok := obj["message"].(string); ok && m != "" {
-			report(-1, m)
+		logLine(line)
+	})
+
+	var summary *restic.SummaryMessage
+	err := restic.Backup(ctx, runner, args, func(msg restic.BackupMessage) {
+		switch msg.Type {
+		case restic.MessageTypeStatus:
+			fillProgress(msg.Status, item)
+			emit(Event{
+				Type:             EventItemProgress,
+				BackupID:         backupID,
+				TotalItems:       totalItems,
+				CompletedItems:   completedItems,
+				Item:             item,
+				BytesDone:        msg.Status.BytesDone,
+				BytesTotal:       msg.Status.TotalBytes,
+				PercentDone:      msg.Status.PercentDone,
+				FilesDone:        msg.Status.FilesDone,
+				TotalFiles:       msg.Status.TotalFiles,
+				SecondsRemaining: msg.Status.SecondsRemaining,
+			})
+		case restic.MessageTypeSummary:
+			summary = msg.Summary
+		case restic.MessageTypeError:
+			emit(Event{Type: EventLog, BackupID: backupID, Message: "RESTIC ERROR: " + msg.Error.Text()})
+		case restic.MessageTypeVerboseStatus:
+			if msg.VerboseStatus.Item != "" {
+				emit(Event{Type: EventLog, BackupID: backupID, Message: msg.VerboseStatus.Action + " " + msg.VerboseStatus.Item})
+			}
 		}
+	}, logLine)
+
+	return summary, err
+}
+
+// fillProgress computes the total, percent and ETA from the item size.
+// restic reports no total for --stdin-from-command, which every item uses.
+func fillProgress(status *restic.StatusMessage, item *Item) {
+	if status.TotalBytes > 0 || item == nil || item.Size <= 0 {
+		return
+	}
+
+	status.TotalBytes = item.Size
+	status.PercentDone = min(float64(status.BytesDone)/float64(item.Size), 1)
+	if status.BytesDone > 0 && status.SecondsElapsed > 0 {
+		remaining := max(item.Size-status.BytesDone, 0)
+		status.SecondsRemaining = remaining * status.SecondsElapsed / status.BytesDone
 	}
 }
 
-func probeLVSizeGB(lvPath string) float64 {
-	cmd := exec.Command("lvs", "--units", "g", "-o", "lv_size", "--noheadings", "--nosuffix", lvPath)
-	out, err := cmd.CombinedOutput()
+// deviceSize returns the size of a block device in bytes.
+func deviceSize(path string) (int64, error) {
+	out, err := exec.Command("/usr/sbin/blockdev", "--getsize64", path).CombinedOutput()
 	if err != nil {
-		return -1
+		return 0, fmt.Errorf("failed to read size of %s: %v\n%s", path, err, string(out))
 	}
-	s := strings.TrimSpace(string(out))
-	s = strings.Fields(s)[0]
-	v, err := strconv.ParseFloat(s, 64)
+
+	size, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
 	if err != nil {
-		return -1
+		return 0, fmt.Errorf("failed to read size of %s: %v", path, err)
 	}
-	return v
+
+	return size, nil
 }
 
-func countBackupItems() int {
+// isNoisyStderr reports restic and dd chatter that would only clutter the run
+// logs.
+func isNoisyStderr(line string) bool {
+	return strings.HasPrefix(line, "subprocess /bin/dd:") ||
+		strings.Contains(line, "using parent snapshot") ||
+		strings.Contains(line, "old cache directories")
+}
+
+func itemDoneEvent(backupID string, item *Item, totalItems int, completedItems int, summary *restic.SummaryMessage) Event {
+	event := Event{
+		Type:           EventItemDone,
+		BackupID:       backupID,
+		TotalItems:     totalItems,
+		CompletedItems: completedItems,
+		Item:           item,
+		PercentDone:    1,
+		Summary:        summary,
+	}
+	if summary != nil {
+		event.BytesDone = summary.TotalBytesProcessed
+		event.BytesTotal = summary.TotalBytesProcessed
+		event.FilesDone = summary.TotalFilesProcessed
+		event.TotalFiles = summary.TotalFilesProcessed
+		event.DataAdded = summary.DataAdded
+		event.SnapshotID = summary.SnapshotID
+	}
+
+	return event
+}
+
+// plannedBackupItems lists the items a run backs up: every disk and the
+// config file of each guest if it exists, with the repository of the guest
+// for the job target repository.
+func plannedBackupItems(repository string) []config.PlannedItem {
+	items := make([]config.PlannedItem, 0)
 	if config.Get() == nil {
-		return 0
+		return items
 	}
 
-	total := 0
 	for _, vm := range config.Get().VMs {
-		total += len(vm.Disks)
-		configFile := filepath.Join("/etc/pve/lxc", fmt.Sprintf("%d.conf", vm.ID))
-		if vm.IsVM {
-			configFile = filepath.Join("/etc/pve/qemu-server", fmt.Sprintf("%d.conf", vm.ID))
+		target := config.TargetRepository(vm, repository)
+		for _, disk := range vm.Disks {
+			items = append(items, config.PlannedItem{
+				ConfiguredItem: config.ConfiguredItem{VMID: vm.ID, SnapshotType: "disk", SrcFile: disk},
+				Repository:     target,
+			})
 		}
+		configFile := config.ConfigFilePath(vm)
 		if _, err := os.Stat(configFile); err == nil {
-			total++
+			items = append(items, config.PlannedItem{
+				ConfiguredItem: config.ConfiguredItem{VMID: vm.ID, SnapshotType: "config", SrcFile: configFile},
+				Repository:     target,
+			})
 		}
 	}
 
-	return total
+	return items
 }
@@ -0,0 +1,54 @@
+package backups
+
+import (
+	"time"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+)
+
+func ListVMHistory(vmID int, limit int) ([]models.BackupItem, error) {
+	return repo.ListBackupItemsForVM(vmID, limit)
+}
+
+func recordBackupItem(runID uint, event Event, startedAt time.Time) error {
+	if event.Item == nil {
+		return nil
+	}
+
+	finishedAt := time.Now()
+	item := &models.BackupItem{
+		BackupRunID: runID,
+		BackupID:    event.BackupID,
+		Repository:  event.Item.Repository,
+		VMID:        event.Item.VM.ID,
+		VMName:      event.Item.VM.Name,
+		ItemType:    event.Item.ItemType,
+		SrcFile:     event.Item.SrcFile,
+		DestFile:    event.Item.DestFile,
+		SnapshotID:  event.SnapshotID,
+		StartedAt:   startedAt,
+		FinishedAt:  finishedAt,
+	}
+
+	item.DurationSeconds = finishedAt.Sub(startedAt).Seconds()
+	if summary := event.Summary; summary != nil {
+		item.DataAdded = summary.DataAdded
+		item.DataAddedPacked = summary.DataAddedPacked
+		item.TotalBytesProcessed = summary.TotalBytesProcessed
+		if summary.TotalDuration > 0 {
+			item.DurationSeconds = summary.TotalDuration
+		}
+	}
+
+	if item.DurationSeconds > 0 {
+		item.Throughput = float64(item.TotalBytesProcessed) / item.DurationSeconds
+	}
+	// DedupRatio is the number of bytes read per byte that had to be stored;
+	// it stays 0 when the item added no new data at all.
+	if item.DataAdded > 0 {
+		item.DedupRatio = float64(item.TotalBytesProcessed) / float64(item.DataAdded)
+	}
+
+	return repo.CreateBackupItem(item)
+}
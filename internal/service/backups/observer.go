@@ -1,6 +1,11 @@
 package backups
 
-import "prostic/internal/config"
+import (
+	"sync"
+
+	"prostic/internal/config"
+	"prostic/internal/restic"
+)
 
 type EventType string
 
@@ -15,21 +20,35 @@ const (
 )
 
 type Item struct {
-	VM       config.VM
-	ItemType string
-	SrcFile  string
-	DestFile string
+	VM         config.VM
+	ItemType   string
+	SrcFile    string
+	DestFile   string
+	Repository string
+	// Size is the expected number of bytes, used for progress when restic
+	// cannot tell the size of its stdin.
+	Size int64
 }
 
 type Event struct {
-	Type           EventType
-	BackupID       string
-	TotalItems     int
-	CompletedItems int
-	Item           *Item
-	BytesDone      int64
-	BytesTotal     int64
-	Message        string
+	Type             EventType
+	BackupID         string
+	TotalItems       int
+	CompletedItems   int
+	Item             *Item
+	BytesDone        int64
+	BytesTotal       int64
+	PercentDone      float64
+	FilesDone        int64
+	TotalFiles       int64
+	SecondsRemaining int64
+	DataAdded        int64
+	SnapshotID       string
+	Summary          *restic.SummaryMessage
+	Message          string
+	// PlannedItems is set on EventRunStarted with every item the run will
+	// back up.
+	PlannedItems []config.PlannedItem
 }
 
 type Observer interface {
@@ -42,6 +61,35 @@ func (fn ObserverFunc) OnEvent(event Event) {
 	fn(event)
 }
 
+var (
+	observersMu sync.Mutex
+	observers   []Observer
+)
+
+// AddObserver registers an observer that receives the events of every run
+// started through StartBackup, e.g. for notifications.
+func AddObserver(observer Observer) {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	observers = append(observers, observer)
+}
+
+// withBroadcast passes every event to observer and then to all registered
+// observers.
+func withBroadcast(observer Observer) Observer {
+	return ObserverFunc(func(event Event) {
+		observer.OnEvent(event)
+
+		observersMu.Lock()
+		registered := append([]Observer(nil), observers...)
+		observersMu.Unlock()
+
+		for _, registeredObserver := range registered {
+			registeredObserver.OnEvent(event)
+		}
+	})
+}
+
 type noopObserver struct{}
 
 func (noopObserver) OnEvent(Event) {}
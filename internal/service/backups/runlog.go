@@ -0,0 +1,158 @@
+package backups
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/util"
+)
+
+// persistInterval is how often new lines of a running backup are written to
+// the database.
+const persistInterval = time.Second
+
+var logger = util.GroupLogger("backups")
+
+// runLog collects the messages of a backup run. Messages are stored as log
+// lines every persistInterval while the run is going so the output of a run
+// survives a crash, and only the last util.MaxLogLines are kept.
+type runLog struct {
+	runID   uint
+	mu      sync.Mutex
+	lines   []string
+	seq     int
+	pending []models.LogLine
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newRunLog(runID uint) *runLog {
+	return &runLog{runID: runID}
+}
+
+func (l *runLog) add(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, line := range strings.Split(message, "\n") {
+		l.seq++
+		line = util.TruncateLogLine(util.Redact(line))
+		l.lines = append(l.lines, line)
+		l.pending = append(l.pending, models.LogLine{
+			Source:    repo.LogSourceBackupRun,
+			SourceID:  l.runID,
+			Seq:       l.seq,
+			Line:      line,
+			CreatedAt: time.Now(),
+		})
+	}
+	if len(l.lines) > util.MaxLogLines {
+		l.lines = append([]string(nil), l.lines[len(l.lines)-util.MaxLogLines:]...)
+	}
+}
+
+func (l *runLog) addf(format string, args ...interface{}) {
+	l.add(fmt.Sprintf(format, args...))
+}
+
+func (l *runLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lines := l.lines
+	if dropped := l.seq - len(l.lines); dropped > 0 {
+		lines = append([]string{util.DroppedLogLines(dropped)}, lines...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// startPersisting writes queued lines to the database until stopPersisting
+// is called.
+func (l *runLog) startPersisting() {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(persistInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				l.persist()
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (l *runLog) stopPersisting() {
+	close(l.stop)
+	<-l.done
+	l.persist()
+}
+
+// persist writes the queued lines and removes stored lines that fell out of
+// the kept window.
+func (l *runLog) persist() {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	seq := l.seq
+	l.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	if err := repo.CreateLogLines(pending); err != nil {
+		logger.Warnf("failed to store log lines of backup run %d: %v", l.runID, err)
+		return
+	}
+	if seq > util.MaxLogLines {
+		if err := repo.DeleteLogLines(repo.LogSourceBackupRun, l.runID, seq-util.MaxLogLines); err != nil {
+			logger.Warnf("failed to rotate log lines of backup run %d: %v", l.runID, err)
+		}
+	}
+}
+
+// RecoverInterrupted marks runs that were still running when prostic stopped
+// as failed and rebuilds their logs from the stored lines.
+func RecoverInterrupted() error {
+	runs, err := repo.ListUnfinishedBackupRuns()
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		lines, err := repo.ListLogLines(repo.LogSourceBackupRun, run.ID)
+		if err != nil {
+			return err
+		}
+
+		var logs []string
+		if len(lines) > 0 && lines[0].Seq > 1 {
+			logs = append(logs, util.DroppedLogLines(lines[0].Seq-1))
+		}
+		for _, line := range lines {
+			logs = append(logs, line.Line)
+		}
+		logs = append(logs, "Error: interrupted, prostic stopped while the backup was running")
+
+		if err := repo.FinishBackupRun(run.ID, StatusFailed, strings.Join(logs, "\n"), run.BackupID, run.CompletedItems); err != nil {
+			return err
+		}
+		if err := repo.DeleteLogLines(repo.LogSourceBackupRun, run.ID, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package backups
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"prostic/internal/db/repo"
+	"prostic/internal/util"
+)
+
+// newTestRunLog returns the log of runID and removes its stored lines when
+// the test ends.
+func newTestRunLog(t *testing.T, runID uint) *runLog {
+	t.Helper()
+
+	t.Cleanup(func() {
+		if err := repo.DeleteLogLines(repo.LogSourceBackupRun, runID, 0); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return newRunLog(runID)
+}
+
+func storedLines(t *testing.T, runID uint) []int {
+	t.Helper()
+
+	lines, err := repo.ListLogLines(repo.LogSourceBackupRun, runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqs := make([]int, 0, len(lines))
+	for _, line := range lines {
+		seqs = append(seqs, line.Seq)
+	}
+
+	return seqs
+}
+
+func TestRunLogPersistsInBatches(t *testing.T) {
+	logs := newTestRunLog(t, 1)
+	logs.add("first\nsecond")
+	logs.addf("third %d", 3)
+
+	if stored := storedLines(t, 1); len(stored) != 0 {
+		t.Fatalf("stored %v before persisting, want nothing", stored)
+	}
+
+	logs.persist()
+	if stored := storedLines(t, 1); fmt.Sprint(stored) != "[1 2 3]" {
+		t.Errorf("stored %v, want [1 2 3]", stored)
+	}
+	if got := logs.String(); got != "first\nsecond\nthird 3" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// nothing new is queued after a persist
+	logs.persist()
+	if stored := storedLines(t, 1); len(stored) != 3 {
+		t.Errorf("stored %d lines after a second persist, want 3", len(stored))
+	}
+}
+
+func TestRunLogKeepsTheLastLines(t *testing.T) {
+	logs := newTestRunLog(t, 2)
+	logs.startPersisting()
+	for i := 1; i <= util.MaxLogLines+5; i++ {
+		logs.addf("line %d", i)
+	}
+	logs.stopPersisting()
+
+	stored := storedLines(t, 2)
+	if len(stored) != util.MaxLogLines || stored[0] != 6 {
+		t.Errorf("stored %d lines starting at %d, want %d starting at 6", len(stored), stored[0], util.MaxLogLines)
+	}
+	if got := logs.String(); !strings.HasPrefix(got, util.DroppedLogLines(5)+"\nline 6\n") {
+		t.Errorf("String() starts with %q", got[:40])
+	}
+}
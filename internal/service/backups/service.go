@@ -1,16 +1,20 @@
 package backups
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	appconfig "prostic/internal/config"
 	"prostic/internal/db/models"
 	"prostic/internal/db/repo"
 	cacheservice "prostic/internal/service/cache"
 	runnerservice "prostic/internal/service/runner"
+	"prostic/internal/util"
 )
 
 const (
@@ -19,6 +23,8 @@ const (
 	StatusFailed  = "failed"
 )
 
+var ErrUnknownRepository = errors.New("unknown repository")
+
 type LiveStatus struct {
 	Running            bool       `json:"running"`
 	RunnerBusy         bool       `json:"runnerBusy"`
@@ -27,6 +33,7 @@ type LiveStatus struct {
 	BackupRunID        *uint      `json:"backupRunID,omitempty"`
 	BackupID           string     `json:"backupID,omitempty"`
 	Trigger            string     `json:"trigger,omitempty"`
+	Repository         string     `json:"repository,omitempty"`
 	StartedAt          *time.Time `json:"startedAt,omitempty"`
 	TotalItems         int        `json:"totalItems"`
 	CompletedItems     int        `json:"completedItems"`
@@ -38,18 +45,44 @@ type LiveStatus struct {
 	CurrentBytesDone   int64      `json:"currentBytesDone"`
 	CurrentBytesTotal  int64      `json:"currentBytesTotal"`
 	CurrentItemStarted *time.Time `json:"currentItemStarted,omitempty"`
+	CurrentPercentDone float64    `json:"currentPercentDone"`
+	CurrentFilesDone   int64      `json:"currentFilesDone"`
+	CurrentTotalFiles  int64      `json:"currentTotalFiles"`
+	CurrentETASeconds  int64      `json:"currentETASeconds"`
+	LastSnapshotID     string     `json:"lastSnapshotID,omitempty"`
+	DataAdded          int64      `json:"dataAdded"`
 	LastMessage        string     `json:"lastMessage,omitempty"`
 	CronExpression     string     `json:"cronExpression"`
 }
 
+// RunFinishedHook is called with the stored run once it has finished and the
+// runner is free again.
+type RunFinishedHook func(run models.BackupRun)
+
 var (
+	hooksMu     sync.Mutex
+	runHooks    []RunFinishedHook
 	liveMu      sync.Mutex
 	liveStatus  = LiveStatus{}
 	schedulerMu sync.Mutex
 	lastTickKey string
+	// cron caches the backup schedule of the settings, which only changes
+	// through UpdateCron.
+	cronMu     sync.Mutex
+	cronLoaded bool
+	cron       string
 )
 
-func StartBackup(trigger string) (*models.BackupRun, error) {
+// StartBackup starts a run in the background. An empty repository backs up
+// to the configured default.
+func StartBackup(trigger string, repository string) (*models.BackupRun, error) {
+	if repository == "" {
+		repository = appconfig.DefaultRepository()
+	}
+	if appconfig.FindRepository(repository) == nil {
+		return nil, ErrUnknownRepository
+	}
+
 	handle, err := runnerservice.Start("backup", "backup")
 	if err != nil {
 		return nil, err
@@ -57,6 +90,7 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 
 	run := &models.BackupRun{
 		Trigger:    trigger,
+		Repository: repository,
 		Status:     StatusRunning,
 		StartedAt:  time.Now(),
 		TotalItems: 0,
@@ -70,6 +104,7 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 		status.Running = true
 		status.BackupRunID = &run.ID
 		status.Trigger = trigger
+		status.Repository = run.Repository
 		status.StartedAt = &run.StartedAt
 		status.TotalItems = 0
 		status.CompletedItems = 0
@@ -81,6 +116,12 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 		status.CurrentBytesDone = 0
 		status.CurrentBytesTotal = 0
 		status.CurrentItemStarted = nil
+		status.CurrentPercentDone = 0
+		status.CurrentFilesDone = 0
+		status.CurrentTotalFiles = 0
+		status.CurrentETASeconds = 0
+		status.LastSnapshotID = ""
+		status.DataAdded = 0
 		status.LastMessage = ""
 		status.BackupID = ""
 	})
@@ -88,7 +129,11 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 	go func(run *models.BackupRun) {
 		defer handle.Release()
 
-		var logs strings.Builder
+		logs := newRunLog(run.ID)
+		logs.startPersisting()
+		var totals models.BackupRun
+		var writtenRepositories []string
+		itemStartedAt := time.Now()
 		observer := ObserverFunc(func(event Event) {
 			switch event.Type {
 			case EventRunStarted:
@@ -96,18 +141,25 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 					status.BackupID = event.BackupID
 					status.TotalItems = event.TotalItems
 				})
+				plannedItems, _ := json.Marshal(event.PlannedItems)
 				_ = repo.UpdateBackupRun(run.ID, map[string]interface{}{
-					"backup_id":   event.BackupID,
-					"total_items": event.TotalItems,
+					"backup_id":     event.BackupID,
+					"total_items":   event.TotalItems,
+					"planned_items": string(plannedItems),
 				})
 			case EventItemStarted:
 				now := time.Now()
+				itemStartedAt = now
 				setLiveStatus(func(status *LiveStatus) {
 					status.CompletedItems = event.CompletedItems
 					status.TotalItems = event.TotalItems
 					status.CurrentBytesDone = 0
 					status.CurrentBytesTotal = event.BytesTotal
 					status.CurrentItemStarted = &now
+					status.CurrentPercentDone = 0
+					status.CurrentFilesDone = 0
+					status.CurrentTotalFiles = 0
+					status.CurrentETASeconds = 0
 					if event.Item != nil {
 						status.CurrentVMName = event.Item.VM.Name
 						status.CurrentItemType = event.Item.ItemType
@@ -122,40 +174,57 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 					status.TotalItems = event.TotalItems
 					status.CurrentBytesDone = event.BytesDone
 					status.CurrentBytesTotal = event.BytesTotal
+					status.CurrentPercentDone = event.PercentDone
+					status.CurrentFilesDone = event.FilesDone
+					status.CurrentTotalFiles = event.TotalFiles
+					status.CurrentETASeconds = event.SecondsRemaining
 				})
 			case EventItemDone:
+				if event.Item != nil && !slices.Contains(writtenRepositories, event.Item.Repository) {
+					writtenRepositories = append(writtenRepositories, event.Item.Repository)
+				}
+				if event.Summary != nil {
+					totals.DataAdded += event.Summary.DataAdded
+					totals.DataAddedPacked += event.Summary.DataAddedPacked
+					totals.TotalBytesProcessed += event.Summary.TotalBytesProcessed
+					totals.TotalFilesProcessed += event.Summary.TotalFilesProcessed
+				}
 				setLiveStatus(func(status *LiveStatus) {
 					status.CompletedItems = event.CompletedItems
 					status.TotalItems = event.TotalItems
 					status.CurrentBytesDone = event.BytesDone
 					status.CurrentBytesTotal = event.BytesTotal
+					status.CurrentPercentDone = event.PercentDone
+					status.CurrentETASeconds = 0
+					status.LastSnapshotID = event.SnapshotID
+					status.DataAdded = totals.DataAdded
 				})
 				_ = repo.UpdateBackupRun(run.ID, map[string]interface{}{
-					"completed_items": event.CompletedItems,
-					"total_items":     event.TotalItems,
+					"completed_items":       event.CompletedItems,
+					"total_items":           event.TotalItems,
+					"data_added":            totals.DataAdded,
+					"data_added_packed":     totals.DataAddedPacked,
+					"total_bytes_processed": totals.TotalBytesProcessed,
+					"total_files_processed": totals.TotalFilesProcessed,
 				})
+				if err := recordBackupItem(run.ID, event, itemStartedAt); err != nil {
+					logs.addf("Failed to record item statistics: %v", err)
+				}
 			case EventLog:
 				if event.Message != "" {
-					if logs.Len() > 0 {
-						logs.WriteString("\n")
-					}
-					logs.WriteString(event.Message)
+					logs.add(event.Message)
 					setLiveStatus(func(status *LiveStatus) {
 						status.LastMessage = event.Message
 					})
 				}
 			case EventRunFailed:
 				if event.Message != "" {
-					if logs.Len() > 0 {
-						logs.WriteString("\n")
-					}
-					logs.WriteString(event.Message)
+					logs.add(event.Message)
 				}
 			}
 		})
 
-		err := RunBackupWithObserver(observer)
-		finalLogs := logs.String()
+		err := RunBackupWithObserver(context.Background(), run.Repository, withBroadcast(observer))
 		backupID := getLiveStatus().BackupID
 		completedItems := getLiveStatus().CompletedItems
 
@@ -166,42 +235,45 @@ func StartBackup(trigger string) (*models.BackupRun, error) {
 			status.CurrentItemStarted = nil
 		})
 
-		refreshResult, refreshErr := cacheservice.RefreshAll()
+		refreshResult, refreshErr := cacheservice.RefreshBackup(backupID, writtenRepositories)
 		if refreshErr != nil {
-			if finalLogs != "" && !strings.HasSuffix(finalLogs, "\n") {
-				finalLogs += "\n"
-			}
-			finalLogs += fmt.Sprintf("Cache refresh failed: %v", refreshErr)
+			logs.addf("Cache refresh failed: %v", refreshErr)
 		} else if refreshResult != nil {
-			if finalLogs != "" && !strings.HasSuffix(finalLogs, "\n") {
-				finalLogs += "\n"
-			}
-			finalLogs += fmt.Sprintf("Cache refresh finished. Snapshots cached: %d", refreshResult.SnapshotCount)
+			logs.addf("Cache refresh finished. Snapshots cached: %d", refreshResult.SnapshotCount)
 		}
 
+		status := StatusSuccess
 		if err != nil {
-			if finalLogs != "" && !strings.HasSuffix(finalLogs, "\n") {
-				finalLogs += "\n"
-			}
-			finalLogs += fmt.Sprintf("Error: %v", err)
-			_ = repo.FinishBackupRun(run.ID, StatusFailed, finalLogs, backupID, completedItems)
-		} else {
-			_ = repo.FinishBackupRun(run.ID, StatusSuccess, finalLogs, backupID, completedItems)
+			logs.addf("Error: %v", err)
+			status = StatusFailed
+		}
+		logs.stopPersisting()
+		if repo.FinishBackupRun(run.ID, status, logs.String(), backupID, completedItems) == nil {
+			_ = repo.DeleteLogLines(repo.LogSourceBackupRun, run.ID, 0)
 		}
 
 		clearLiveStatus()
+		handle.Release()
+		notifyRunFinished(run.ID)
 	}(run)
 
 	return run, nil
 }
 
+// GetLiveStatus returns the progress of the running backup with the backup
+// schedule.
 func GetLiveStatus() LiveStatus {
-	status := getLiveStatus()
-	settings, err := repo.GetSettings()
-	if err == nil && settings != nil {
-		status.CronExpression = settings.BackupCron
-	}
+	status := GetProgress()
+	status.CronExpression = CronExpression()
+
+	return status
+}
 
+// GetProgress returns the progress of the running backup and the state of
+// the runner without CronExpression. It never touches the database, so
+// observers may call it on every event.
+func GetProgress() LiveStatus {
+	status := getLiveStatus()
 	runner := runnerservice.GetStatus()
 	status.RunnerBusy = runner.Running
 	status.RunnerKind = runner.Kind
@@ -217,12 +289,41 @@ func GetLiveStatus() LiveStatus {
 func UpdateCron(expression string) error {
 	expression = strings.TrimSpace(expression)
 	if expression != "" {
-		if _, err := cronMatches(expression, time.Now().In(time.Local)); err != nil {
+		if _, err := util.CronMatches(expression, time.Now().In(time.Local)); err != nil {
 			return errors.New("invalid cron expression")
 		}
 	}
 
-	return repo.UpdateBackupCron(expression)
+	if err := repo.UpdateBackupCron(expression); err != nil {
+		return err
+	}
+
+	cronMu.Lock()
+	defer cronMu.Unlock()
+	cron = expression
+	cronLoaded = true
+
+	return nil
+}
+
+// CronExpression returns the backup schedule, reading the settings only
+// once.
+func CronExpression() string {
+	cronMu.Lock()
+	defer cronMu.Unlock()
+
+	if !cronLoaded {
+		settings, err := repo.GetSettings()
+		if err != nil {
+			return ""
+		}
+		if settings != nil {
+			cron = settings.BackupCron
+		}
+		cronLoaded = true
+	}
+
+	return cron
 }
 
 func ListRuns(limit int) ([]models.BackupRun, error) {
@@ -235,7 +336,7 @@ func SchedulerTick(now time.Time) {
 		return
 	}
 
-	matches, err := cronMatches(settings.BackupCron, now)
+	matches, err := util.CronMatches(settings.BackupCron, now)
 	if err != nil || !matches {
 		return
 	}
@@ -249,7 +350,28 @@ func SchedulerTick(now time.Time) {
 	lastTickKey = key
 	schedulerMu.Unlock()
 
-	_, _ = StartBackup("scheduled")
+	_, _ = StartBackup("scheduled", "")
+}
+
+func OnRunFinished(hook RunFinishedHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	runHooks = append(runHooks, hook)
+}
+
+func notifyRunFinished(runID uint) {
+	run, err := repo.GetBackupRun(runID)
+	if err != nil || run == nil {
+		return
+	}
+
+	hooksMu.Lock()
+	hooks := append([]RunFinishedHook(nil), runHooks...)
+	hooksMu.Unlock()
+
+	for _, hook := range hooks {
+		hook(*run)
+	}
 }
 
 func setLiveStatus(update func(*LiveStatus)) {
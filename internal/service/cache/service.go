@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
+
 	repostatsservice "prostic/internal/service/repo_stats"
 	snapshotservice "prostic/internal/service/snapshots"
 )
@@ -9,17 +12,33 @@ type RefreshResult struct {
 	SnapshotCount int `json:"snapshotCount"`
 }
 
+// RefreshAll refreshes snapshots and statistics of every repository. The
+// result is returned even when some repositories failed to refresh.
 func RefreshAll() (*RefreshResult, error) {
-	snapshotCount, err := snapshotservice.RefreshSnapshotCache()
-	if err != nil {
-		return nil, err
-	}
-
-	if err := repostatsservice.RefreshRepoStatCache(); err != nil {
-		return nil, err
-	}
+	snapshotCount, snapshotErr := snapshotservice.RefreshSnapshotCache()
+	statsErr := repostatsservice.RefreshRepoStatCache()
 
 	return &RefreshResult{
 		SnapshotCount: snapshotCount,
-	}, nil
+	}, errors.Join(snapshotErr, statsErr)
+}
+
+// RefreshBackup refreshes only the snapshots of backupID and the statistics
+// of the repositories the backup wrote to.
+func RefreshBackup(backupID string, repositories []string) (*RefreshResult, error) {
+	result := &RefreshResult{}
+	var errs []error
+	for _, repository := range repositories {
+		count, err := snapshotservice.RefreshBackupID(repository, backupID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("repository %s: %w", repository, err))
+		}
+		result.SnapshotCount += count
+
+		if err := repostatsservice.RefreshRepository(repository); err != nil {
+			errs = append(errs, fmt.Errorf("repository %s: %w", repository, err))
+		}
+	}
+
+	return result, errors.Join(errs...)
 }
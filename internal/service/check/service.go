@@ -0,0 +1,203 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/restic"
+	taskservice "prostic/internal/service/tasks"
+	"prostic/internal/util"
+)
+
+const TaskPurposeCheck = "check"
+
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// checkTimeout replaces restic.timeout since reading data can take many
+// hours on large repositories.
+const checkTimeout = 24 * time.Hour
+
+var ErrUnknownRepository = errors.New("unknown repository")
+
+type Summary struct {
+	Repository string     `json:"repository"`
+	Status     string     `json:"status"`
+	CheckedAt  *time.Time `json:"checkedAt"`
+	AgeSeconds int64      `json:"ageSeconds"`
+}
+
+var (
+	schedulerMu sync.Mutex
+	lastTickKey string
+	logger      = util.GroupLogger("check")
+)
+
+// Start checks the given repositories one after another in a background
+// task. No repositories means all repositories covered by the check config.
+func Start(repositories []string) (*models.Task, error) {
+	if len(repositories) == 0 {
+		repositories = appconfig.CheckRepositories()
+	}
+	for _, repository := range repositories {
+		if appconfig.FindRepository(repository) == nil {
+			return nil, ErrUnknownRepository
+		}
+	}
+
+	return taskservice.StartBackgroundTask(TaskPurposeCheck, func(task *models.Task, logs *taskservice.Log) error {
+		var errs []error
+		for _, repository := range repositories {
+			if err := Run(logs, repository, task.ID); err != nil {
+				errs = append(errs, fmt.Errorf("repository %s: %w", repository, err))
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+func SchedulerTick(now time.Time) {
+	cfg := appconfig.Get()
+	if cfg == nil || strings.TrimSpace(cfg.Check.Cron) == "" {
+		return
+	}
+
+	matches, err := util.CronMatches(cfg.Check.Cron, now)
+	if err != nil || !matches {
+		return
+	}
+
+	key := now.In(time.Local).Format("2006-01-02 15:04")
+	schedulerMu.Lock()
+	if lastTickKey == key {
+		schedulerMu.Unlock()
+		return
+	}
+	lastTickKey = key
+	schedulerMu.Unlock()
+
+	if _, err := Start(nil); err != nil {
+		logger.Warnf("Could not start scheduled check: %v", err)
+	}
+}
+
+// Run checks one repository and stores the result.
+func Run(logs *taskservice.Log, repository string, taskID uint) error {
+	check := &models.RepoCheck{
+		Repository: repository,
+		TaskID:     taskID,
+		StartedAt:  time.Now(),
+	}
+
+	subsetIndex, subsetCount, err := nextSubset(repository)
+	if err != nil {
+		return err
+	}
+	if subsetCount > 0 {
+		check.SubsetIndex = subsetIndex
+		check.SubsetCount = subsetCount
+		check.ReadDataSubset = fmt.Sprintf("%d/%d", subsetIndex, subsetCount)
+	}
+
+	logs.WriteString(fmt.Sprintf("Checking repository %s", repository))
+	if check.ReadDataSubset != "" {
+		logs.WriteString(fmt.Sprintf(" (reading data subset %s)", check.ReadDataSubset))
+	}
+	logs.WriteString("\n")
+
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return err
+	}
+
+	var stderr strings.Builder
+	runner = runner.WithStderr(func(line string) {
+		stderr.WriteString(line)
+		stderr.WriteString("\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	output, checkErr := restic.Check(ctx, runner, check.ReadDataSubset)
+	finishedAt := time.Now()
+	check.FinishedAt = &finishedAt
+	check.DurationSeconds = finishedAt.Sub(check.StartedAt).Seconds()
+	check.Output = output
+	check.ErrorOutput = stderr.String()
+	check.Status = StatusSuccess
+	if checkErr != nil {
+		check.Status = StatusFailed
+		if check.ErrorOutput == "" {
+			check.ErrorOutput = checkErr.Error()
+		}
+	}
+
+	logs.WriteString(output)
+	if !strings.HasSuffix(output, "\n") {
+		logs.WriteString("\n")
+	}
+	logs.WriteString(check.ErrorOutput)
+
+	if err := repo.CreateRepoCheck(check); err != nil {
+		return err
+	}
+
+	return checkErr
+}
+
+// nextSubset continues the rotation through 1/n..n/n so that all data is read
+// once every n checks. Only successful checks advance it, a subset that
+// failed is read again. It returns zero values when data is not read.
+func nextSubset(repository string) (int, int, error) {
+	cfg := appconfig.Get()
+	if cfg == nil || cfg.Check.ReadDataPercent <= 0 {
+		return 0, 0, nil
+	}
+
+	count := (100 + cfg.Check.ReadDataPercent - 1) / cfg.Check.ReadDataPercent
+	last, err := repo.GetLatestSubsetCheck(repository, StatusSuccess)
+	if err != nil {
+		return 0, 0, err
+	}
+	if last == nil || last.SubsetCount != count {
+		return 1, count, nil
+	}
+
+	return last.SubsetIndex%count + 1, count, nil
+}
+
+func GetSummary(repository string) (*Summary, error) {
+	check, err := repo.GetLatestRepoCheck(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Repository: repository}
+	if check == nil {
+		return summary, nil
+	}
+
+	summary.Status = check.Status
+	checkedAt := check.StartedAt
+	if check.FinishedAt != nil {
+		checkedAt = *check.FinishedAt
+	}
+	summary.CheckedAt = &checkedAt
+	summary.AgeSeconds = int64(time.Since(checkedAt).Seconds())
+	return summary, nil
+}
+
+func ListChecks(repository string, limit int) ([]models.RepoCheck, error) {
+	return repo.ListRepoChecks(repository, limit)
+}
@@ -0,0 +1,193 @@
+package coverage
+
+import (
+	"errors"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+)
+
+const (
+	StatusOK         = "ok"
+	StatusStale      = "stale"
+	StatusIncomplete = "incomplete"
+	StatusNever      = "never"
+)
+
+var ErrUnknownVM = errors.New("unknown vm")
+
+type ItemCoverage struct {
+	SnapshotType   string           `json:"snapshotType"`
+	SrcFile        string           `json:"srcFile"`
+	LatestSnapshot *models.Snapshot `json:"latestSnapshot"`
+	AgeSeconds     int64            `json:"ageSeconds"`
+}
+
+type Guest struct {
+	VMID int    `json:"vmid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Repository is where scheduled backups of the guest go. Backups in
+	// other repositories count too unless the guest sets its own.
+	Repository string `json:"repository"`
+	Status     string `json:"status"`
+	// LastGoodBackupID is the newest backup holding every configured item.
+	LastGoodBackupID   string         `json:"lastGoodBackupID,omitempty"`
+	LastGoodRepository string         `json:"lastGoodRepository,omitempty"`
+	LastGoodBackupAt   *time.Time     `json:"lastGoodBackupAt"`
+	LastGoodAgeSeconds int64          `json:"lastGoodAgeSeconds"`
+	LatestBackupID     string         `json:"latestBackupID,omitempty"`
+	RestorePoints      int            `json:"restorePoints"`
+	StaleAfterSeconds  int64          `json:"staleAfterSeconds"`
+	Items              []ItemCoverage `json:"items"`
+}
+
+func List() ([]Guest, error) {
+	cfg := appconfig.Get()
+	guests := make([]Guest, 0)
+	if cfg == nil {
+		return guests, nil
+	}
+
+	for _, vm := range cfg.VMs {
+		guest, err := forVM(vm)
+		if err != nil {
+			return nil, err
+		}
+		guests = append(guests, *guest)
+	}
+
+	return guests, nil
+}
+
+func Get(vmID int) (*Guest, error) {
+	vm := appconfig.FindVM(vmID)
+	if vm == nil {
+		return nil, ErrUnknownVM
+	}
+
+	return forVM(*vm)
+}
+
+func forVM(vm appconfig.VM) (*Guest, error) {
+	guest := &Guest{
+		VMID:              vm.ID,
+		Name:              vm.Name,
+		Type:              "lxc",
+		Repository:        appconfig.TargetRepository(vm, ""),
+		StaleAfterSeconds: int64(appconfig.StaleThreshold(vm).Seconds()),
+		Items:             make([]ItemCoverage, 0),
+	}
+	if vm.IsVM {
+		guest.Type = "vm"
+	}
+
+	// a backup started for another repository is a valid restore point,
+	// unless the guest is pinned to its own repository
+	vmID := vm.ID
+	page, err := repo.QuerySnapshots(repo.SnapshotQuery{
+		Repository: vm.Repository,
+		VMID:       &vmID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	items := guestItems(vm)
+	latest := make(map[appconfig.ConfiguredItem]*models.Snapshot, len(items))
+	// snapshots are sorted newest first, so the first backup ID seen is the
+	// latest and the first complete one is the last good backup
+	backups := make(map[string]map[appconfig.ConfiguredItem]bool)
+	var order []string
+	for i := range page.Snapshots {
+		snapshot := &page.Snapshots[i]
+		item := appconfig.ConfiguredItem{VMID: vm.ID, SnapshotType: snapshot.SnapshotType, SrcFile: snapshot.SrcFile}
+		if latest[item] == nil {
+			latest[item] = snapshot
+		}
+
+		if snapshot.BackupID == "" {
+			continue
+		}
+		if backups[snapshot.BackupID] == nil {
+			backups[snapshot.BackupID] = make(map[appconfig.ConfiguredItem]bool)
+			order = append(order, snapshot.BackupID)
+		}
+		backups[snapshot.BackupID][item] = true
+	}
+	guest.RestorePoints = len(order)
+
+	for _, item := range items {
+		coverage := ItemCoverage{SnapshotType: item.SnapshotType, SrcFile: item.SrcFile}
+		if snapshot := latest[item]; snapshot != nil {
+			coverage.LatestSnapshot = snapshot
+			coverage.AgeSeconds = int64(now.Sub(snapshot.Time).Seconds())
+		}
+		guest.Items = append(guest.Items, coverage)
+	}
+
+	if len(order) > 0 {
+		guest.LatestBackupID = order[0]
+	}
+	for _, backupID := range order {
+		if !containsAll(backups[backupID], items) {
+			continue
+		}
+
+		newest := newestSnapshot(page.Snapshots, backupID)
+		guest.LastGoodBackupID = backupID
+		guest.LastGoodRepository = newest.Repository
+		guest.LastGoodBackupAt = &newest.Time
+		guest.LastGoodAgeSeconds = int64(now.Sub(newest.Time).Seconds())
+		break
+	}
+
+	switch {
+	case len(page.Snapshots) == 0:
+		guest.Status = StatusNever
+	case guest.LastGoodBackupID == "" || guest.LastGoodBackupID != guest.LatestBackupID:
+		guest.Status = StatusIncomplete
+	case guest.LastGoodAgeSeconds > guest.StaleAfterSeconds:
+		guest.Status = StatusStale
+	default:
+		guest.Status = StatusOK
+	}
+
+	return guest, nil
+}
+
+func guestItems(vm appconfig.VM) []appconfig.ConfiguredItem {
+	items := make([]appconfig.ConfiguredItem, 0)
+	for _, item := range appconfig.ConfiguredItems() {
+		if item.VMID == vm.ID {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func containsAll(present map[appconfig.ConfiguredItem]bool, items []appconfig.ConfiguredItem) bool {
+	for _, item := range items {
+		if !present[item] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newestSnapshot returns the newest snapshot of backupID, taken when the
+// backup of the guest finished.
+func newestSnapshot(snapshots []models.Snapshot, backupID string) models.Snapshot {
+	for _, snapshot := range snapshots {
+		if snapshot.BackupID == backupID {
+			return snapshot
+		}
+	}
+
+	return models.Snapshot{}
+}
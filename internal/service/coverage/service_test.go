@@ -0,0 +1,112 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+)
+
+// Guest 100 is backed up to local by default and guest 200 is pinned to
+// archive.
+const coverageConfig = `vms:
+  - name: web
+    id: 100
+    disks: [/dev/pve/vm-100-disk-0]
+  - name: db
+    id: 200
+    disks: [/dev/pve/vm-200-disk-0]
+    repository: archive
+repositories:
+  - name: local
+    env: {RESTIC_REPOSITORY: /srv/local, RESTIC_PASSWORD: secret}
+  - name: archive
+    env: {RESTIC_REPOSITORY: /srv/archive, RESTIC_PASSWORD: secret}
+  - name: offsite
+    env: {RESTIC_REPOSITORY: /srv/offsite, RESTIC_PASSWORD: secret}
+`
+
+func loadConfig(t *testing.T) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(coverageConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appconfig.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedBackup stores a snapshot of every configured item of the guest.
+func seedBackup(t *testing.T, repository string, vmID int, backupID string, taken time.Time) {
+	t.Helper()
+
+	var snapshots []models.Snapshot
+	for _, item := range appconfig.ConfiguredItems() {
+		if item.VMID != vmID {
+			continue
+		}
+		snapshots = append(snapshots, models.Snapshot{
+			SnapshotID:   backupID + repository + item.SrcFile,
+			Time:         taken,
+			BackupID:     backupID,
+			VMID:         &vmID,
+			SnapshotType: item.SnapshotType,
+			SrcFile:      item.SrcFile,
+		})
+	}
+	if _, err := repo.MergeSnapshots(repository, snapshots); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetCountsBackupsOfEveryRepository(t *testing.T) {
+	loadConfig(t)
+	now := time.Now()
+	// a job-level backup to offsite is newer than the scheduled one
+	seedBackup(t, "local", 100, "scheduled", now.Add(-48*time.Hour))
+	seedBackup(t, "offsite", 100, "manual", now.Add(-time.Hour))
+	// guest 200 only counts backups of its own repository
+	seedBackup(t, "local", 200, "manual", now.Add(-time.Hour))
+
+	tests := []struct {
+		name              string
+		vmID              int
+		wantStatus        string
+		wantRepository    string
+		wantLastGood      string
+		wantLastGoodRepo  string
+		wantRestorePoints int
+	}{
+		{name: "default target", vmID: 100, wantStatus: StatusOK, wantRepository: "local", wantLastGood: "manual", wantLastGoodRepo: "offsite", wantRestorePoints: 2},
+		{name: "pinned repository", vmID: 200, wantStatus: StatusNever, wantRepository: "archive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guest, err := Get(tt.vmID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if guest.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", guest.Status, tt.wantStatus)
+			}
+			if guest.Repository != tt.wantRepository {
+				t.Errorf("Repository = %q, want %q", guest.Repository, tt.wantRepository)
+			}
+			if guest.LastGoodBackupID != tt.wantLastGood {
+				t.Errorf("LastGoodBackupID = %q, want %q", guest.LastGoodBackupID, tt.wantLastGood)
+			}
+			if guest.LastGoodRepository != tt.wantLastGoodRepo {
+				t.Errorf("LastGoodRepository = %q, want %q", guest.LastGoodRepository, tt.wantLastGoodRepo)
+			}
+			if guest.RestorePoints != tt.wantRestorePoints {
+				t.Errorf("RestorePoints = %d, want %d", guest.RestorePoints, tt.wantRestorePoints)
+			}
+		})
+	}
+}
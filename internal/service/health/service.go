@@ -0,0 +1,175 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db"
+	"prostic/internal/restic"
+	"prostic/internal/util"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+const (
+	// repositoryTimeout bounds the probe of the default repository.
+	repositoryTimeout = 10 * time.Second
+	// repositoryCacheTTL keeps frequent probes of a load balancer from
+	// running restic on every request.
+	repositoryCacheTTL = 30 * time.Second
+	// schedulerMaxAge is how long the scheduler may go without a tick; it
+	// ticks every 30 seconds.
+	schedulerMaxAge = 2 * time.Minute
+)
+
+type Check struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type Report struct {
+	Status string  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+var (
+	heartbeatMu   sync.Mutex
+	lastHeartbeat time.Time
+
+	repositoryMu      sync.Mutex
+	repositoryChecked time.Time
+	repositoryResult  Check
+)
+
+// SchedulerHeartbeat is called by the scheduler loop on every tick.
+func SchedulerHeartbeat(now time.Time) {
+	heartbeatMu.Lock()
+	defer heartbeatMu.Unlock()
+	lastHeartbeat = now
+}
+
+// Ready runs all readiness checks. Messages are redacted, they never carry
+// repository locations or credentials.
+func Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK}
+	for _, check := range []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"database", checkDatabase},
+		{"config", checkConfig},
+		{"restic", checkRestic},
+		{"scheduler", checkScheduler},
+	} {
+		report.Checks = append(report.Checks, runCheck(ctx, check.name, check.run))
+	}
+	report.Checks = append(report.Checks, repositoryCheck())
+
+	for _, check := range report.Checks {
+		if check.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, name string, run func(ctx context.Context) error) Check {
+	startedAt := time.Now()
+	check := Check{Name: name, Status: StatusOK}
+	if err := run(ctx); err != nil {
+		check.Status = StatusFail
+		check.Message = util.Redact(err.Error())
+	}
+	check.DurationMs = time.Since(startedAt).Milliseconds()
+
+	return check
+}
+
+func checkDatabase(ctx context.Context) error {
+	database, err := db.Get()
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+func checkConfig(context.Context) error {
+	if appconfig.Get() == nil {
+		return errors.New("config not loaded")
+	}
+
+	return nil
+}
+
+func checkRestic(context.Context) error {
+	if _, err := exec.LookPath(restic.BinaryPath()); err != nil {
+		return fmt.Errorf("restic binary not found at %s", restic.BinaryPath())
+	}
+
+	return nil
+}
+
+func checkScheduler(context.Context) error {
+	heartbeatMu.Lock()
+	last := lastHeartbeat
+	heartbeatMu.Unlock()
+
+	if last.IsZero() {
+		return errors.New("scheduler not started")
+	}
+	if age := time.Since(last); age > schedulerMaxAge {
+		return fmt.Errorf("last scheduler tick %s ago", age.Round(time.Second))
+	}
+
+	return nil
+}
+
+// repositoryCheck probes the default repository, reusing a recent result.
+// A locked repository is reachable and counts as ready. The probe does not
+// run under the request context, so a client hanging up cannot cache a
+// failure for everyone else.
+func repositoryCheck() Check {
+	repositoryMu.Lock()
+	defer repositoryMu.Unlock()
+
+	if !repositoryChecked.IsZero() && time.Since(repositoryChecked) < repositoryCacheTTL {
+		return repositoryResult
+	}
+
+	repositoryResult = runCheck(context.Background(), "repository", func(ctx context.Context) error {
+		runner, err := restic.ForRepository(appconfig.DefaultRepository())
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, repositoryTimeout)
+		defer cancel()
+
+		state := restic.GetRepositoryState(ctx, runner)
+		switch state.Status {
+		case restic.RepositoryOK, restic.RepositoryLocked:
+			return nil
+		}
+		return fmt.Errorf("repository %s is %s", appconfig.DefaultRepository(), state.Status)
+	})
+	repositoryChecked = time.Now()
+
+	return repositoryResult
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/restic/restictest"
+)
+
+func loadConfig(t *testing.T) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("repositories:\n  - name: local\n    env:\n      RESTIC_REPOSITORY: /srv/restic\n      RESTIC_PASSWORD: secret\n")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appconfig.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepositoryCheckUsesItsOwnDeadline(t *testing.T) {
+	loadConfig(t)
+	repositoryChecked = time.Time{}
+	t.Cleanup(func() {
+		repositoryChecked = time.Time{}
+	})
+	fake := restictest.Install(t, &restictest.Fake{})
+
+	startedAt := time.Now()
+	check := repositoryCheck()
+	if check.Status != StatusOK {
+		t.Fatalf("Status = %q (%s), want %q", check.Status, check.Message, StatusOK)
+	}
+
+	calls := fake.Calls()
+	if len(calls) == 0 {
+		t.Fatal("repository was not probed")
+	}
+	for _, call := range calls {
+		if call.Deadline.Before(startedAt.Add(repositoryTimeout)) || call.Deadline.After(time.Now().Add(repositoryTimeout)) {
+			t.Errorf("%v: deadline %s, want %s after the probe started", call.Args, call.Deadline, repositoryTimeout)
+		}
+	}
+
+	// the result is reused within repositoryCacheTTL
+	if cached := repositoryCheck(); cached != check {
+		t.Errorf("second check = %+v, want cached %+v", cached, check)
+	}
+	if got := len(fake.Calls()); got != len(calls) {
+		t.Errorf("second check ran %d more commands, want none", got-len(calls))
+	}
+}
@@ -0,0 +1,247 @@
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/restic"
+	taskservice "prostic/internal/service/tasks"
+)
+
+const (
+	TaskPurposeKeyAdd    = "key-add"
+	TaskPurposeKeyRemove = "key-remove"
+	TaskPurposeKeyRotate = "key-rotate"
+)
+
+var (
+	ErrUnknownRepository  = errors.New("unknown repository")
+	ErrUnknownKey         = errors.New("unknown key")
+	ErrAmbiguousKey       = errors.New("key ID matches more than one key")
+	ErrCurrentKey         = errors.New("the key used by prostic cannot be removed")
+	ErrEmptyPassword      = errors.New("password is required")
+	ErrRotateNotSupported = errors.New("rotation requires RESTIC_PASSWORD_FILE")
+)
+
+type AddOptions struct {
+	Repository string `json:"repository"`
+	Password   string `json:"password"`
+	Host       string `json:"host"`
+	User       string `json:"user"`
+}
+
+func List(ctx context.Context, repository string) ([]restic.Key, error) {
+	runner, err := runnerFor(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	return restic.ListKeys(ctx, runner)
+}
+
+// FindKey resolves a full or abbreviated key ID.
+func FindKey(ctx context.Context, repository string, keyID string) (*restic.Key, error) {
+	keys, err := List(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *restic.Key
+	for i := range keys {
+		if keyID == "" || !strings.HasPrefix(keys[i].ID, keyID) {
+			continue
+		}
+		if match != nil {
+			return nil, ErrAmbiguousKey
+		}
+		match = &keys[i]
+	}
+	if match == nil {
+		return nil, ErrUnknownKey
+	}
+
+	return match, nil
+}
+
+// ValidateAdd checks a request before the task starts so that errors are
+// reported to the caller instead of ending up in the task log.
+func ValidateAdd(options AddOptions) error {
+	if appconfig.FindRepository(options.Repository) == nil {
+		return ErrUnknownRepository
+	}
+	if options.Password == "" {
+		return ErrEmptyPassword
+	}
+
+	return nil
+}
+
+// RunAdd adds a key for the given password. The password only ever reaches
+// restic through a temporary file and is never logged.
+func RunAdd(logs *taskservice.Log, options AddOptions) error {
+	logs.WriteString(fmt.Sprintf("Add key to repository %s\n", options.Repository))
+
+	if err := ValidateAdd(options); err != nil {
+		return err
+	}
+
+	runner, err := runnerFor(options.Repository)
+	if err != nil {
+		return err
+	}
+	runner = withLogs(runner, logs)
+
+	passwordFile, err := writePasswordFile("", options.Password)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(passwordFile)
+
+	output, err := restic.AddKey(context.Background(), runner, passwordFile, options.Host, options.User)
+	writeOutput(logs, output)
+	return err
+}
+
+func RunRemove(logs *taskservice.Log, repository string, keyID string) error {
+	logs.WriteString(fmt.Sprintf("Remove key %s from repository %s\n", keyID, repository))
+
+	key, err := FindKey(context.Background(), repository, keyID)
+	if err != nil {
+		return err
+	}
+	if key.Current {
+		return ErrCurrentKey
+	}
+
+	runner, err := runnerFor(repository)
+	if err != nil {
+		return err
+	}
+	runner = withLogs(runner, logs)
+
+	output, err := restic.RemoveKey(context.Background(), runner, key.ID)
+	writeOutput(logs, output)
+	return err
+}
+
+// ValidateRotate checks that the password of the repository lives in a file
+// prostic can replace. Inline passwords and password commands have to be
+// rotated by hand.
+func ValidateRotate(repository string) (string, error) {
+	config := appconfig.FindRepository(repository)
+	if config == nil {
+		return "", ErrUnknownRepository
+	}
+
+	passwordFile := config.Env["RESTIC_PASSWORD_FILE"]
+	if passwordFile == "" || config.Env["RESTIC_PASSWORD"] != "" || config.Env["RESTIC_PASSWORD_COMMAND"] != "" {
+		return "", ErrRotateNotSupported
+	}
+
+	return passwordFile, nil
+}
+
+// RunRotate replaces the password of the key prostic uses with a random one
+// and writes it to RESTIC_PASSWORD_FILE. The new password is staged next to
+// the password file so the final rename cannot cross file systems.
+func RunRotate(logs *taskservice.Log, repository string) error {
+	logs.WriteString(fmt.Sprintf("Rotate key of repository %s\n", repository))
+
+	passwordFile, err := ValidateRotate(repository)
+	if err != nil {
+		return err
+	}
+
+	runner, err := runnerFor(repository)
+	if err != nil {
+		return err
+	}
+	runner = withLogs(runner, logs)
+
+	password, err := generatePassword()
+	if err != nil {
+		return err
+	}
+
+	stagedFile, err := writePasswordFile(filepath.Dir(passwordFile), password)
+	if err != nil {
+		return err
+	}
+
+	output, err := restic.ChangePassword(context.Background(), runner, stagedFile)
+	writeOutput(logs, output)
+	if err != nil {
+		_ = os.Remove(stagedFile)
+		return err
+	}
+
+	if err := os.Rename(stagedFile, passwordFile); err != nil {
+		logs.WriteString(fmt.Sprintf("The key was changed but %s could not be replaced. The new password is stored in %s.\n", passwordFile, stagedFile))
+		return err
+	}
+
+	logs.WriteString(fmt.Sprintf("New password written to %s\n", passwordFile))
+	return nil
+}
+
+func runnerFor(repository string) (restic.Runner, error) {
+	if appconfig.FindRepository(repository) == nil {
+		return nil, ErrUnknownRepository
+	}
+
+	return restic.ForRepository(repository)
+}
+
+func withLogs(runner restic.Runner, logs *taskservice.Log) restic.Runner {
+	return runner.WithStderr(func(line string) {
+		logs.WriteString(line)
+		logs.WriteString("\n")
+	})
+}
+
+func writeOutput(logs *taskservice.Log, output string) {
+	logs.WriteString(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		logs.WriteString("\n")
+	}
+}
+
+func writePasswordFile(dir string, password string) (string, error) {
+	file, err := os.CreateTemp(dir, ".prostic-key-*")
+	if err != nil {
+		return "", err
+	}
+
+	if err := file.Chmod(0o600); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if _, err := file.WriteString(password); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+func generatePassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
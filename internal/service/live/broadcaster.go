@@ -0,0 +1,62 @@
+package live
+
+import (
+	"sync"
+)
+
+// subscriberBuffer is how many messages a client may fall behind before it
+// is disconnected. It reconnects and starts over with the replayed state.
+const subscriberBuffer = 256
+
+// Message is one server-sent event.
+type Message struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type broadcaster struct {
+	mu          sync.Mutex
+	nextID      uint64
+	subscribers map[chan Message]struct{}
+}
+
+var hub = &broadcaster{subscribers: make(map[chan Message]struct{})}
+
+// Subscribe registers a client. The channel is closed when the client falls
+// too far behind or unsubscribe is called.
+func Subscribe() (<-chan Message, func()) {
+	messages := make(chan Message, subscriberBuffer)
+
+	hub.mu.Lock()
+	hub.subscribers[messages] = struct{}{}
+	hub.mu.Unlock()
+
+	unsubscribe := func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		if _, ok := hub.subscribers[messages]; ok {
+			delete(hub.subscribers, messages)
+			close(messages)
+		}
+	}
+
+	return messages, unsubscribe
+}
+
+// publish sends a message to every client without blocking the caller.
+func publish(messageType string, data interface{}) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.nextID++
+	message := Message{ID: hub.nextID, Type: messageType, Data: data}
+	for subscriber := range hub.subscribers {
+		select {
+		case subscriber <- message:
+		default:
+			delete(hub.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package live
+
+import (
+	"prostic/internal/db/models"
+	backupservice "prostic/internal/service/backups"
+	runnerservice "prostic/internal/service/runner"
+	taskservice "prostic/internal/service/tasks"
+	"prostic/internal/util"
+)
+
+const (
+	TypeRunner       = "runner"
+	TypeBackup       = "backup"
+	TypeBackupStatus = "backup_status"
+	TypeTask         = "task"
+	TypeTaskLog      = "task_log"
+	TypeTaskFinished = "task_finished"
+)
+
+// BackupEvent is an event of the backup observer as sent to clients.
+type BackupEvent struct {
+	Type             string  `json:"type"`
+	BackupID         string  `json:"backupID,omitempty"`
+	TotalItems       int     `json:"totalItems"`
+	CompletedItems   int     `json:"completedItems"`
+	VMID             *int    `json:"vmid,omitempty"`
+	VMName           string  `json:"vmName,omitempty"`
+	ItemType         string  `json:"itemType,omitempty"`
+	SrcFile          string  `json:"srcFile,omitempty"`
+	Repository       string  `json:"repository,omitempty"`
+	BytesDone        int64   `json:"bytesDone,omitempty"`
+	BytesTotal       int64   `json:"bytesTotal,omitempty"`
+	PercentDone      float64 `json:"percentDone,omitempty"`
+	FilesDone        int64   `json:"filesDone,omitempty"`
+	TotalFiles       int64   `json:"totalFiles,omitempty"`
+	SecondsRemaining int64   `json:"secondsRemaining,omitempty"`
+	DataAdded        int64   `json:"dataAdded,omitempty"`
+	SnapshotID       string  `json:"snapshotID,omitempty"`
+	Message          string  `json:"message,omitempty"`
+}
+
+type TaskLogLine struct {
+	TaskID uint   `json:"taskID"`
+	Seq    int    `json:"seq"`
+	Line   string `json:"line"`
+}
+
+type TaskFinished struct {
+	Task  models.Task `json:"task"`
+	Error string      `json:"error,omitempty"`
+}
+
+// Start subscribes to the backup observer, background tasks and the runner.
+func Start() {
+	backupservice.AddObserver(backupservice.ObserverFunc(func(event backupservice.Event) {
+		publish(TypeBackup, fromBackupEvent(event))
+		publish(TypeBackupStatus, backupStatus())
+	}))
+	// the progress is cleared after the last event of a run
+	backupservice.OnRunFinished(func(models.BackupRun) {
+		publish(TypeBackupStatus, backupStatus())
+	})
+	taskservice.OnTaskStarted(func(task models.Task) {
+		publish(TypeTask, taskservice.RunningTask{Task: task})
+	})
+	taskservice.OnTaskLog(func(taskID uint, seq int, line string) {
+		publish(TypeTaskLog, TaskLogLine{TaskID: taskID, Seq: seq, Line: line})
+	})
+	taskservice.OnTaskFinished(func(task models.Task, err error) {
+		finished := TaskFinished{Task: task}
+		// the stored logs were already streamed line by line
+		finished.Task.Logs = ""
+		if err != nil {
+			finished.Error = util.Redact(err.Error())
+		}
+		publish(TypeTaskFinished, finished)
+	})
+	runnerservice.OnChange(func(status runnerservice.Status) {
+		publish(TypeRunner, status)
+	})
+}
+
+// Snapshot returns the current state a client receives when it connects:
+// the runner, the backup progress and every running task with its output so
+// far.
+func Snapshot() []Message {
+	messages := []Message{
+		{Type: TypeRunner, Data: runnerservice.GetStatus()},
+		{Type: TypeBackupStatus, Data: backupservice.GetLiveStatus()},
+	}
+	for _, task := range taskservice.Running() {
+		messages = append(messages, Message{Type: TypeTask, Data: task})
+	}
+
+	return messages
+}
+
+// backupStatus is the backup progress as published on every backup event.
+// It is built from memory, progress ticks must not wait on the database.
+func backupStatus() backupservice.LiveStatus {
+	status := backupservice.GetProgress()
+	status.CronExpression = backupservice.CronExpression()
+
+	return status
+}
+
+func fromBackupEvent(event backupservice.Event) BackupEvent {
+	converted := BackupEvent{
+		Type:             string(event.Type),
+		BackupID:         event.BackupID,
+		TotalItems:       event.TotalItems,
+		CompletedItems:   event.CompletedItems,
+		BytesDone:        event.BytesDone,
+		BytesTotal:       event.BytesTotal,
+		PercentDone:      event.PercentDone,
+		FilesDone:        event.FilesDone,
+		TotalFiles:       event.TotalFiles,
+		SecondsRemaining: event.SecondsRemaining,
+		DataAdded:        event.DataAdded,
+		SnapshotID:       event.SnapshotID,
+		Message:          util.Redact(event.Message),
+	}
+	if event.Item != nil {
+		vmID := event.Item.VM.ID
+		converted.VMID = &vmID
+		converted.VMName = event.Item.VM.Name
+		converted.ItemType = event.Item.ItemType
+		converted.SrcFile = event.Item.SrcFile
+		converted.Repository = event.Item.Repository
+	}
+
+	return converted
+}
@@ -0,0 +1,155 @@
+package locks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/restic"
+	taskservice "prostic/internal/service/tasks"
+)
+
+const TaskPurposeUnlock = "unlock"
+
+var ErrUnknownRepository = errors.New("unknown repository")
+
+type LockInfo struct {
+	restic.Lock
+	AgeSeconds int64 `json:"ageSeconds"`
+}
+
+func List(ctx context.Context, repository string) ([]LockInfo, error) {
+	if appconfig.FindRepository(repository) == nil {
+		return nil, ErrUnknownRepository
+	}
+
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := restic.ListLocks(ctx, runner)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	infos := make([]LockInfo, 0, len(locks))
+	for _, lock := range locks {
+		infos = append(infos, LockInfo{
+			Lock:       lock,
+			AgeSeconds: int64(now.Sub(lock.Time).Seconds()),
+		})
+	}
+
+	return infos, nil
+}
+
+func RunUnlock(logs *taskservice.Log, repository string, removeAll bool) error {
+	logs.WriteString(fmt.Sprintf("Unlock repository %s", repository))
+	if removeAll {
+		logs.WriteString(" (removing all locks)")
+	}
+	logs.WriteString("\n")
+
+	return unlock(context.Background(), logs, repository, removeAll)
+}
+
+// RemoveStaleLocks is run before a backup. Locks older than threshold are
+// removed; when newer locks exist only restic's own rule applies, which keeps
+// locks of live processes and of other hosts, so any stale lock that is left
+// is reported as an error instead of letting the backup fail on it later.
+func RemoveStaleLocks(ctx context.Context, repository string, threshold time.Duration, log func(message string)) error {
+	if threshold <= 0 {
+		return nil
+	}
+
+	locks, err := List(ctx, repository)
+	if err != nil {
+		return err
+	}
+
+	old := staleLocks(locks, threshold)
+	if len(old) == 0 {
+		return nil
+	}
+	for _, lock := range old {
+		log(fmt.Sprintf("Lock %s in %s held by %s@%s (pid %d) since %s", shortID(lock.ID), repository, lock.Username, lock.Hostname, lock.PID, lock.Time.Format(time.RFC3339)))
+	}
+
+	var logs strings.Builder
+	removeAll := len(old) == len(locks)
+	if !removeAll {
+		log(fmt.Sprintf("%d newer lock(s) in %s are kept, only locks restic considers stale are removed", len(locks)-len(old), repository))
+	}
+	err = unlock(ctx, &logs, repository, removeAll)
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if line != "" {
+			log(line)
+		}
+	}
+	if err != nil || removeAll {
+		return err
+	}
+
+	locks, err = List(ctx, repository)
+	if err != nil {
+		return err
+	}
+	if left := staleLocks(locks, threshold); len(left) > 0 {
+		ids := make([]string, 0, len(left))
+		for _, lock := range left {
+			ids = append(ids, shortID(lock.ID)+" ("+lock.Hostname+")")
+		}
+		return fmt.Errorf("%d lock(s) older than %s are still held next to newer locks: %s; remove all locks once no other job uses the repository", len(left), threshold, strings.Join(ids, ", "))
+	}
+
+	return nil
+}
+
+// staleLocks returns the locks that are at least threshold old.
+func staleLocks(locks []LockInfo, threshold time.Duration) []LockInfo {
+	var old []LockInfo
+	for _, lock := range locks {
+		if time.Duration(lock.AgeSeconds)*time.Second >= threshold {
+			old = append(old, lock)
+		}
+	}
+
+	return old
+}
+
+func unlock(ctx context.Context, logs io.StringWriter, repository string, removeAll bool) error {
+	if appconfig.FindRepository(repository) == nil {
+		return ErrUnknownRepository
+	}
+
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return err
+	}
+	runner = runner.WithStderr(func(line string) {
+		logs.WriteString(line)
+		logs.WriteString("\n")
+	})
+
+	output, err := restic.Unlock(ctx, runner, removeAll)
+	logs.WriteString(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		logs.WriteString("\n")
+	}
+
+	return err
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
+}
@@ -0,0 +1,206 @@
+package locks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/restic/restictest"
+)
+
+func loadConfig(t *testing.T) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("repositories:\n  - name: local\n    env:\n      RESTIC_REPOSITORY: /srv/restic\n      RESTIC_PASSWORD: secret\n")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appconfig.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lockJSON is a lock file as printed by `restic cat lock`.
+func lockJSON(at time.Time, hostname string, pid int) string {
+	return `{"time":"` + at.Format(time.RFC3339Nano) + `","exclusive":false,"hostname":"` + hostname + `","username":"root","pid":` + strconv.Itoa(pid) + `,"uid":0,"gid":0}`
+}
+
+func TestList(t *testing.T) {
+	loadConfig(t)
+	now := time.Now()
+	fake := restictest.Install(t, &restictest.Fake{Respond: func(call restictest.Call) (string, error) {
+		switch {
+		case slices.Equal(call.Args, []string{"list", "locks", "--no-lock"}):
+			return "aaaaaaaaaaaa\nbbbbbbbbbbbb\ncccccccccccc\n", nil
+		case call.Args[2] == "aaaaaaaaaaaa":
+			return lockJSON(now.Add(-2*time.Hour), "pve1", 4211), nil
+		case call.Args[2] == "bbbbbbbbbbbb":
+			// released between listing and reading
+			return "", errors.New("no matching ID found")
+		default:
+			return lockJSON(now.Add(-time.Minute), "pve2", 17), nil
+		}
+	}})
+
+	locks, err := List(context.Background(), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(locks) != 2 {
+		t.Fatalf("got %d locks, want 2", len(locks))
+	}
+	if locks[0].ID != "aaaaaaaaaaaa" || locks[0].Hostname != "pve1" || locks[0].PID != 4211 {
+		t.Errorf("first lock = %+v", locks[0])
+	}
+	if age := time.Duration(locks[0].AgeSeconds) * time.Second; age < 2*time.Hour || age > 2*time.Hour+time.Minute {
+		t.Errorf("first lock age = %v", age)
+	}
+	if locks[1].ID != "cccccccccccc" || locks[1].Hostname != "pve2" {
+		t.Errorf("second lock = %+v", locks[1])
+	}
+	for _, call := range fake.Calls() {
+		if call.Repository != "local" || call.Env["RESTIC_REPOSITORY"] != "/srv/restic" {
+			t.Errorf("%v ran against %s %v", call.Args, call.Repository, call.Env)
+		}
+	}
+}
+
+func TestListUnknownRepository(t *testing.T) {
+	loadConfig(t)
+	fake := restictest.Install(t, &restictest.Fake{})
+
+	if _, err := List(context.Background(), "offsite"); !errors.Is(err, ErrUnknownRepository) {
+		t.Errorf("List() error = %v, want ErrUnknownRepository", err)
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("ran %d commands", len(calls))
+	}
+}
+
+// lockRepository answers lock commands for a set of locks. Plain unlock only
+// removes the locks in removable, like restic removes locks of dead processes
+// on the same host.
+func lockRepository(locks map[string]string, removable map[string]bool) func(call restictest.Call) (string, error) {
+	return func(call restictest.Call) (string, error) {
+		switch call.Args[0] {
+		case "list":
+			ids := make([]string, 0, len(locks))
+			for id := range locks {
+				ids = append(ids, id)
+			}
+			slices.Sort(ids)
+			return strings.Join(ids, "\n") + "\n", nil
+		case "cat":
+			lock, ok := locks[call.Args[2]]
+			if !ok {
+				return "", errors.New("no matching ID found")
+			}
+			return lock, nil
+		case "unlock":
+			for id := range locks {
+				if slices.Contains(call.Args, "--remove-all") || removable[id] {
+					delete(locks, id)
+				}
+			}
+			return "successfully removed locks\n", nil
+		}
+		return "", errors.New("unexpected command")
+	}
+}
+
+func TestRemoveStaleLocks(t *testing.T) {
+	now := time.Now()
+	stale := lockJSON(now.Add(-3*time.Hour), "pve1", 4211)
+	otherHost := lockJSON(now.Add(-3*time.Hour), "pve2", 880)
+	fresh := lockJSON(now.Add(-time.Minute), "pve1", 5120)
+
+	tests := []struct {
+		name       string
+		threshold  time.Duration
+		locks      map[string]string
+		removable  map[string]bool
+		wantUnlock []string
+		wantLeft   int
+		wantErr    string
+	}{
+		{
+			name:     "disabled",
+			locks:    map[string]string{"aaaaaaaaaaaa": stale},
+			wantLeft: 1,
+		},
+		{
+			name:      "only newer locks",
+			threshold: time.Hour,
+			locks:     map[string]string{"cccccccccccc": fresh},
+			wantLeft:  1,
+		},
+		{
+			name:       "only stale locks",
+			threshold:  time.Hour,
+			locks:      map[string]string{"aaaaaaaaaaaa": stale, "bbbbbbbbbbbb": otherHost},
+			wantUnlock: []string{"unlock", "--remove-all"},
+		},
+		{
+			name:       "stale lock removed by restic next to a newer one",
+			threshold:  time.Hour,
+			locks:      map[string]string{"aaaaaaaaaaaa": stale, "cccccccccccc": fresh},
+			removable:  map[string]bool{"aaaaaaaaaaaa": true},
+			wantUnlock: []string{"unlock"},
+			wantLeft:   1,
+		},
+		{
+			name:       "stale lock of another host next to a newer one",
+			threshold:  time.Hour,
+			locks:      map[string]string{"bbbbbbbbbbbb": otherHost, "cccccccccccc": fresh},
+			wantUnlock: []string{"unlock"},
+			wantLeft:   2,
+			wantErr:    "bbbbbbbb (pve2)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loadConfig(t)
+			fake := restictest.Install(t, &restictest.Fake{Respond: lockRepository(test.locks, test.removable)})
+
+			var logged []string
+			err := RemoveStaleLocks(context.Background(), "local", test.threshold, func(message string) {
+				logged = append(logged, message)
+			})
+			if test.wantErr == "" && err != nil {
+				t.Fatalf("RemoveStaleLocks() error = %v", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("RemoveStaleLocks() error = %v, want it to name %s", err, test.wantErr)
+			}
+
+			var unlocks [][]string
+			for _, call := range fake.Calls() {
+				if call.Args[0] == "unlock" {
+					unlocks = append(unlocks, call.Args)
+				}
+			}
+			if test.wantUnlock == nil && len(unlocks) > 0 {
+				t.Errorf("ran %v, want no unlock", unlocks)
+			}
+			if test.wantUnlock != nil && (len(unlocks) != 1 || !slices.Equal(unlocks[0], test.wantUnlock)) {
+				t.Errorf("ran %v, want %v", unlocks, test.wantUnlock)
+			}
+			if len(test.locks) != test.wantLeft {
+				t.Errorf("%d locks left, want %d", len(test.locks), test.wantLeft)
+			}
+			if test.threshold == 0 && len(fake.Calls()) > 0 {
+				t.Errorf("ran %d commands while disabled", len(fake.Calls()))
+			}
+		})
+	}
+}
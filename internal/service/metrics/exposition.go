@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeGauge   = "gauge"
+	typeCounter = "counter"
+)
+
+// family is one metric in the Prometheus text exposition format.
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+// registry collects families in the order they are first used.
+type registry struct {
+	families []*family
+	byName   map[string]*family
+}
+
+func newRegistry() *registry {
+	return &registry{byName: make(map[string]*family)}
+}
+
+func (r *registry) gauge(name string, help string) *family {
+	return r.family(name, help, typeGauge)
+}
+
+func (r *registry) counter(name string, help string) *family {
+	return r.family(name, help, typeCounter)
+}
+
+func (r *registry) family(name string, help string, kind string) *family {
+	if existing := r.byName[name]; existing != nil {
+		return existing
+	}
+
+	created := &family{name: name, help: help, kind: kind}
+	r.families = append(r.families, created)
+	r.byName[name] = created
+	return created
+}
+
+// add records a sample; labels are given as name, value pairs.
+func (f *family) add(value float64, labels ...string) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+func (r *registry) String() string {
+	var out strings.Builder
+	for _, f := range r.families {
+		out.WriteString("# HELP " + f.name + " " + f.help + "\n")
+		out.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+		for _, s := range f.samples {
+			out.WriteString(f.name)
+			if len(s.labels) > 0 {
+				out.WriteString("{")
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						out.WriteString(",")
+					}
+					out.WriteString(s.labels[i] + `="` + escapeLabel(s.labels[i+1]) + `"`)
+				}
+				out.WriteString("}")
+			}
+			out.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+
+	return out.String()
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
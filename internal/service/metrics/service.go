@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/repo"
+	backupservice "prostic/internal/service/backups"
+	checkservice "prostic/internal/service/check"
+	coverageservice "prostic/internal/service/coverage"
+	replicationservice "prostic/internal/service/replication"
+	runnerservice "prostic/internal/service/runner"
+	slaservice "prostic/internal/service/sla"
+	taskservice "prostic/internal/service/tasks"
+)
+
+// Render collects all metrics in the Prometheus text exposition format.
+// Values are read from the database cache, no restic command is run.
+func Render() (string, error) {
+	metrics := newRegistry()
+	collectors := []func(*registry) error{
+		collectBackup,
+		collectRunner,
+		collectTasks,
+		collectRepositories,
+		collectChecks,
+		collectReplication,
+		collectGuests,
+	}
+	for _, collect := range collectors {
+		if err := collect(metrics); err != nil {
+			return "", err
+		}
+	}
+
+	return metrics.String(), nil
+}
+
+// collectBackup reports the newest finished run of every configured
+// repository. Labels only name the repository, so each series continues
+// across runs whatever triggered them.
+func collectBackup(metrics *registry) error {
+	success := metrics.gauge("prostic_backup_last_run_success", "Whether the last finished backup run of the repository succeeded.")
+	timestamp := metrics.gauge("prostic_backup_last_run_timestamp_seconds", "Finish time of the last backup run of the repository.")
+	duration := metrics.gauge("prostic_backup_last_run_duration_seconds", "Duration of the last backup run of the repository.")
+	processed := metrics.gauge("prostic_backup_last_run_processed_bytes", "Bytes read by the last backup run of the repository.")
+	added := metrics.gauge("prostic_backup_last_run_added_bytes", "Bytes added to the repository by the last backup run.")
+	items := metrics.gauge("prostic_backup_last_run_items", "Items of the last backup run of the repository by state.")
+	lastSuccess := metrics.gauge("prostic_backup_last_success_timestamp_seconds", "Finish time of the last successful backup run of the repository.")
+	if appconfig.Get() == nil {
+		return nil
+	}
+
+	for _, repository := range appconfig.Get().Repositories {
+		run, err := repo.GetLatestFinishedBackupRun(repository.Name, "")
+		if err != nil {
+			return err
+		}
+		if run == nil || run.FinishedAt == nil {
+			continue
+		}
+		success.add(boolValue(run.Status == backupservice.StatusSuccess), "repository", repository.Name)
+		timestamp.add(float64(run.FinishedAt.Unix()), "repository", repository.Name)
+		duration.add(run.FinishedAt.Sub(run.StartedAt).Seconds(), "repository", repository.Name)
+		processed.add(float64(run.TotalBytesProcessed), "repository", repository.Name)
+		added.add(float64(run.DataAdded), "repository", repository.Name)
+		items.add(float64(run.CompletedItems), "repository", repository.Name, "state", "completed")
+		items.add(float64(run.TotalItems), "repository", repository.Name, "state", "total")
+
+		if run.Status != backupservice.StatusSuccess {
+			if run, err = repo.GetLatestFinishedBackupRun(repository.Name, backupservice.StatusSuccess); err != nil {
+				return err
+			}
+		}
+		if run != nil && run.FinishedAt != nil {
+			lastSuccess.add(float64(run.FinishedAt.Unix()), "repository", repository.Name)
+		}
+	}
+
+	return nil
+}
+
+// collectRunner reports the shared runner lock. Jobs are not queued, a job
+// started while the runner is busy is rejected.
+func collectRunner(metrics *registry) error {
+	status := runnerservice.GetStatus()
+	busy := metrics.gauge("prostic_runner_busy", "Whether a job holds the runner, labelled with the job.")
+	busySeconds := metrics.gauge("prostic_runner_busy_seconds", "How long the current job has held the runner.")
+	if !status.Running {
+		busy.add(0)
+		busySeconds.add(0)
+		return nil
+	}
+
+	busy.add(1, "kind", status.Kind, "purpose", status.Purpose)
+	if status.StartedAt != nil {
+		busySeconds.add(time.Since(*status.StartedAt).Seconds())
+	}
+	return nil
+}
+
+func collectTasks(metrics *registry) error {
+	counts, err := repo.CountTasks()
+	if err != nil {
+		return err
+	}
+
+	finished := metrics.counter("prostic_tasks_total", "Finished background tasks by purpose and outcome.")
+	running := metrics.gauge("prostic_tasks_running", "Background tasks that are still running.")
+	runningTotal := 0.0
+	for _, count := range counts {
+		if count.Status == taskservice.StatusRunning {
+			runningTotal += float64(count.Count)
+			continue
+		}
+		finished.add(float64(count.Count), "purpose", count.Purpose, "status", count.Status)
+	}
+	running.add(runningTotal)
+	return nil
+}
+
+func collectRepositories(metrics *registry) error {
+	size := metrics.gauge("prostic_repository_size_bytes", "Stored size of the repository.")
+	uncompressed := metrics.gauge("prostic_repository_uncompressed_size_bytes", "Uncompressed size of the repository data.")
+	ratio := metrics.gauge("prostic_repository_compression_ratio", "Compression ratio of the repository.")
+	blobs := metrics.gauge("prostic_repository_blobs", "Blobs in the repository.")
+	snapshots := metrics.gauge("prostic_repository_snapshots", "Snapshots in the repository as counted by restic.")
+	statsTime := metrics.gauge("prostic_repository_stats_timestamp_seconds", "When the repository statistics were last refreshed.")
+	cachedSnapshots := metrics.gauge("prostic_cached_snapshots", "Snapshots in the local snapshot cache.")
+	cachedBackups := metrics.gauge("prostic_cached_backups", "Backups in the local snapshot cache.")
+	if appconfig.Get() == nil {
+		return nil
+	}
+
+	for _, repository := range appconfig.Get().Repositories {
+		overview, err := repo.GetSnapshotOverview(repository.Name)
+		if err != nil {
+			return err
+		}
+		cachedSnapshots.add(float64(overview.TotalSnapshots), "repository", repository.Name)
+		cachedBackups.add(float64(overview.TotalBackups), "repository", repository.Name)
+
+		stat, err := repo.GetLatestRepoStat(repository.Name)
+		if err != nil {
+			return err
+		}
+		if stat == nil {
+			continue
+		}
+		size.add(float64(stat.TotalSize), "repository", repository.Name)
+		uncompressed.add(float64(stat.TotalUncompressedSize), "repository", repository.Name)
+		ratio.add(stat.CompressionRatio, "repository", repository.Name)
+		blobs.add(float64(stat.TotalBlobCount), "repository", repository.Name)
+		snapshots.add(float64(stat.SnapshotsCount), "repository", repository.Name)
+		statsTime.add(float64(stat.LastRefreshedAt.Unix()), "repository", repository.Name)
+	}
+
+	return nil
+}
+
+func collectChecks(metrics *registry) error {
+	success := metrics.gauge("prostic_check_last_success", "Whether the last check of the repository succeeded.")
+	timestamp := metrics.gauge("prostic_check_last_timestamp_seconds", "Finish time of the last check of the repository.")
+	if appconfig.Get() == nil {
+		return nil
+	}
+
+	for _, repository := range appconfig.Get().Repositories {
+		summary, err := checkservice.GetSummary(repository.Name)
+		if err != nil {
+			return err
+		}
+		if summary.CheckedAt == nil {
+			continue
+		}
+		success.add(boolValue(summary.Status == checkservice.StatusSuccess), "repository", repository.Name)
+		timestamp.add(float64(summary.CheckedAt.Unix()), "repository", repository.Name)
+	}
+
+	return nil
+}
+
+func collectReplication(metrics *registry) error {
+	statuses, err := replicationservice.GetStatus()
+	if err != nil {
+		return err
+	}
+
+	pending := metrics.gauge("prostic_replication_pending_backups", "Backups not yet copied to the target.")
+	lag := metrics.gauge("prostic_replication_lag_seconds", "Age of the oldest backup not yet copied to the target.")
+	timestamp := metrics.gauge("prostic_replication_last_timestamp_seconds", "When a backup was last copied to the target.")
+	for _, status := range statuses {
+		labels := []string{"name", status.Name, "source", status.Source, "target", status.Target}
+		pending.add(float64(status.PendingBackups), labels...)
+		lag.add(float64(status.LagSeconds), labels...)
+		if status.LastReplicatedAt != nil {
+			timestamp.add(float64(status.LastReplicatedAt.Unix()), labels...)
+		}
+	}
+
+	return nil
+}
+
+func collectGuests(metrics *registry) error {
+	guests, err := coverageservice.List()
+	if err != nil {
+		return err
+	}
+
+	breached := make(map[int]bool)
+	for _, alert := range slaservice.Breaches() {
+		breached[alert.VMID] = true
+	}
+
+	lastSuccess := metrics.gauge("prostic_guest_last_success_timestamp_seconds", "Time of the newest complete backup of the guest.")
+	restorePoints := metrics.gauge("prostic_guest_restore_points", "Backups of the guest in the cache.")
+	stale := metrics.gauge("prostic_guest_stale", "Whether the guest has no complete backup within its stale_after.")
+	slaBreached := metrics.gauge("prostic_guest_sla_breached", "Whether the guest is in breach of its backup SLA.")
+	for _, guest := range guests {
+		labels := []string{"vmid", strconv.Itoa(guest.VMID), "name", guest.Name, "repository", guest.Repository}
+		if guest.LastGoodBackupAt != nil {
+			lastSuccess.add(float64(guest.LastGoodBackupAt.Unix()), labels...)
+		}
+		restorePoints.add(float64(guest.RestorePoints), labels...)
+		stale.add(boolValue(guest.Status != coverageservice.StatusOK), labels...)
+		slaBreached.add(boolValue(breached[guest.VMID]), labels...)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	backupservice "prostic/internal/service/backups"
+	"prostic/internal/util"
+)
+
+var (
+	schedulerMu  sync.Mutex
+	lastTickKeys = make(map[string]string)
+)
+
+// AfterBackup mails the report of run to every email channel that is not in
+// digest mode.
+func AfterBackup(run models.BackupRun) {
+	cfg := appconfig.Get()
+	if cfg == nil {
+		return
+	}
+
+	event := runReportEvent(run)
+	for _, email := range cfg.Notifications.Email {
+		channel := emailChannel{config: email}
+		if !channel.Accepts(event) {
+			continue
+		}
+
+		go func() {
+			_, _ = deliver(channel, event)
+		}()
+	}
+}
+
+// SchedulerTick sends the daily and weekly digests that are due.
+func SchedulerTick(now time.Time) {
+	cfg := appconfig.Get()
+	if cfg == nil {
+		return
+	}
+
+	for _, email := range cfg.Notifications.Email {
+		expression := email.DigestCron()
+		if expression == "" {
+			continue
+		}
+
+		matches, err := util.CronMatches(expression, now)
+		if err != nil || !matches {
+			continue
+		}
+
+		key := now.In(time.Local).Format("2006-01-02 15:04")
+		schedulerMu.Lock()
+		if lastTickKeys[email.Name] == key {
+			schedulerMu.Unlock()
+			continue
+		}
+		lastTickKeys[email.Name] = key
+		schedulerMu.Unlock()
+
+		go sendDigest(email, now)
+	}
+}
+
+// sendDigest mails the runs of the past period. With the failure filter the
+// digest is only sent when a run failed or no backup ran at all.
+func sendDigest(email appconfig.Email, now time.Time) {
+	since := now.Add(-email.DigestPeriod())
+	runs, err := repo.ListBackupRunsSince(since)
+	if err != nil {
+		logger.Warnf("Could not collect runs for digest %s: %v", email.Name, err)
+		return
+	}
+
+	event := digestEvent(email.Digest, since, now, runs)
+	if email.Filter == appconfig.NotifyOnFailure && !event.Failed() {
+		return
+	}
+
+	_, _ = deliver(emailChannel{config: email}, event)
+}
+
+func digestEvent(digest string, since time.Time, now time.Time, runs []models.BackupRun) Event {
+	failed := 0
+	for _, run := range runs {
+		if run.Status == backupservice.StatusFailed {
+			failed++
+		}
+	}
+
+	period := "Daily"
+	if digest == appconfig.DigestWeekly {
+		period = "Weekly"
+	}
+
+	event := Event{
+		Type:    EventDigest,
+		Status:  StatusSuccess,
+		Title:   fmt.Sprintf("%s backup report: %d runs, %d failed", period, len(runs), failed),
+		Message: fmt.Sprintf("%s to %s", since.In(time.Local).Format("2006-01-02 15:04"), now.In(time.Local).Format("2006-01-02 15:04")),
+		Time:    now,
+		runs:    runs,
+	}
+	if failed > 0 || len(runs) == 0 {
+		event.Status = StatusFailed
+	}
+	if len(runs) == 0 {
+		event.Title = fmt.Sprintf("%s backup report: no backups ran", period)
+	}
+
+	return event
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+)
+
+func TestDigestEvent(t *testing.T) {
+	since := time.Date(2024, 5, 6, 8, 0, 0, 0, time.Local)
+	now := since.Add(24 * time.Hour)
+	success := models.BackupRun{Status: "success"}
+	failed := models.BackupRun{Status: "failed"}
+
+	tests := []struct {
+		name       string
+		digest     string
+		runs       []models.BackupRun
+		wantStatus string
+		wantTitle  string
+	}{
+		{name: "all succeeded", digest: appconfig.DigestDaily, runs: []models.BackupRun{success, success}, wantStatus: StatusSuccess, wantTitle: "Daily backup report: 2 runs, 0 failed"},
+		{name: "one failed", digest: appconfig.DigestWeekly, runs: []models.BackupRun{success, failed, success}, wantStatus: StatusFailed, wantTitle: "Weekly backup report: 3 runs, 1 failed"},
+		// a digest without runs means the schedule stopped working
+		{name: "no runs", digest: appconfig.DigestDaily, wantStatus: StatusFailed, wantTitle: "Daily backup report: no backups ran"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := digestEvent(test.digest, since, now, test.runs)
+			if event.Type != EventDigest {
+				t.Errorf("Type = %q, want %q", event.Type, EventDigest)
+			}
+			if event.Status != test.wantStatus {
+				t.Errorf("Status = %q, want %q", event.Status, test.wantStatus)
+			}
+			if event.Failed() != (test.wantStatus == StatusFailed) {
+				t.Errorf("Failed() = %v", event.Failed())
+			}
+			if event.Title != test.wantTitle {
+				t.Errorf("Title = %q, want %q", event.Title, test.wantTitle)
+			}
+			if want := "2024-05-06 08:00 to 2024-05-07 08:00"; event.Message != want {
+				t.Errorf("Message = %q, want %q", event.Message, want)
+			}
+			if len(event.runs) != len(test.runs) {
+				t.Errorf("event carries %d runs, want %d", len(event.runs), len(test.runs))
+			}
+		})
+	}
+}
+
+func TestSchedulerTickSendsEachDigestOnce(t *testing.T) {
+	stub := startSMTPStub(t, &smtpStub{implicit: true})
+	port := stub.listener.Addr().(*net.TCPAddr).Port
+	config := fmt.Sprintf(`notifications:
+  email:
+    - name: daily
+      host: 127.0.0.1
+      port: %d
+      tls: tls
+      from: backup@example.com
+      to: [ops@example.com]
+      digest: daily
+    - name: weekly
+      host: 127.0.0.1
+      port: %d
+      tls: tls
+      from: backup@example.com
+      to: [ops@example.com]
+      digest: weekly
+`, port, port)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appconfig.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	lastTickKeys = make(map[string]string)
+	t.Cleanup(func() {
+		lastTickKeys = make(map[string]string)
+	})
+
+	// Monday at the default digest time sends both digests, Tuesday only
+	// the daily one
+	monday := time.Date(2024, 5, 6, 8, 0, 0, 0, time.Local)
+	SchedulerTick(monday)
+	SchedulerTick(monday.Add(30 * time.Second))
+	SchedulerTick(monday.Add(time.Minute))
+	SchedulerTick(monday.Add(24 * time.Hour))
+
+	var messages []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, _, messages = stub.received(); len(messages) >= 3 {
+			break
+		}
+	}
+	// a duplicate would arrive shortly after the expected digests
+	time.Sleep(100 * time.Millisecond)
+	_, _, messages = stub.received()
+
+	daily, weekly := 0, 0
+	for _, message := range messages {
+		subject, _ := parseEmail(t, message)
+		switch {
+		case strings.HasPrefix(subject, "[prostic] Daily backup report"):
+			daily++
+		case strings.HasPrefix(subject, "[prostic] Weekly backup report"):
+			weekly++
+		default:
+			t.Errorf("unexpected subject %q", subject)
+		}
+	}
+	if daily != 2 || weekly != 1 {
+		t.Errorf("sent %d daily and %d weekly digests, want 2 and 1", daily, weekly)
+	}
+}
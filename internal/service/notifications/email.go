@@ -0,0 +1,241 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	backupservice "prostic/internal/service/backups"
+)
+
+const KindEmail = "email"
+
+// rootCAs verifies the certificates of mail servers, nil uses the system
+// roots; tests replace it.
+var rootCAs *x509.CertPool
+
+// emailChannel sends backup reports. It does not take part in Publish, run
+// reports and digests are handed to it by AfterBackup and SchedulerTick.
+type emailChannel struct {
+	config appconfig.Email
+}
+
+func (e emailChannel) Name() string {
+	return e.config.Name
+}
+
+func (e emailChannel) Kind() string {
+	return KindEmail
+}
+
+func (e emailChannel) Accepts(event Event) bool {
+	switch event.Type {
+	case EventTest, EventDigest:
+		return true
+	case EventRunReport:
+		return e.config.Digest == "" && (e.config.Filter == appconfig.NotifyAlways || event.Failed())
+	}
+
+	return false
+}
+
+func (e emailChannel) Retries() int {
+	return e.config.RetryCount()
+}
+
+func (e emailChannel) Send(ctx context.Context, event Event) (int, error) {
+	message, err := e.compose(event)
+	if err != nil {
+		return 0, permanent(err)
+	}
+
+	err = e.send(ctx, message)
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		// 5xx replies are rejections, like a bad login or an unknown recipient
+		if protocolErr.Code >= 500 {
+			return protocolErr.Code, permanent(err)
+		}
+		return protocolErr.Code, err
+	}
+
+	return 0, err
+}
+
+// compose renders the report of the event as a multipart message with a
+// plaintext and an HTML part. A test event reports the latest run.
+func (e emailChannel) compose(event Event) ([]byte, error) {
+	runs := event.runs
+	title := event.Title
+	if event.Type == EventTest {
+		latest, err := repo.ListBackupRuns(1)
+		if err != nil {
+			return nil, err
+		}
+		runs = latest
+		title = "Test report from prostic"
+	}
+
+	report, err := buildReport(title, event.Message, runs)
+	if err != nil {
+		return nil, err
+	}
+	if event.Type == EventTest {
+		report.Subtitle = "This is a test email, it shows the latest backup run."
+	}
+	text, err := report.renderText()
+	if err != nil {
+		return nil, err
+	}
+	html, err := report.renderHTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writer, err := parts.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	message.WriteString("From: " + e.config.From + "\r\n")
+	message.WriteString("To: " + strings.Join(e.config.To, ", ") + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", "[prostic] "+title) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: multipart/alternative; boundary=" + parts.Boundary() + "\r\n")
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func (e emailChannel) send(ctx context.Context, message []byte) error {
+	timeout := e.config.RequestTimeout()
+	address := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	tlsConfig := &tls.Config{ServerName: e.config.Host, RootCAs: rootCAs}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if e.config.TLS == appconfig.EmailTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.config.TLS == appconfig.EmailTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return permanent(fmt.Errorf("%s does not support STARTTLS", e.config.Host))
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.config.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted
+		// connection to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, err := mail.ParseAddress(e.config.From)
+	if err != nil {
+		return permanent(err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range e.config.To {
+		recipient, err := mail.ParseAddress(to)
+		if err != nil {
+			return permanent(err)
+		}
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// runReportEvent describes a finished run for the per-run email report.
+func runReportEvent(run models.BackupRun) Event {
+	event := Event{
+		Type:     EventRunReport,
+		Status:   StatusSuccess,
+		Title:    fmt.Sprintf("Backup %s succeeded: %d/%d items, %s added", run.BackupID, run.CompletedItems, run.TotalItems, formatBytes(run.DataAdded)),
+		Time:     time.Now(),
+		BackupID: run.BackupID,
+		runs:     []models.BackupRun{run},
+	}
+	if run.Status != backupservice.StatusSuccess {
+		event.Status = StatusFailed
+		event.Title = fmt.Sprintf("Backup %s failed: %d/%d items", run.BackupID, run.CompletedItems, run.TotalItems)
+	}
+	event.Message = fmt.Sprintf("%s backup started %s", run.Trigger, run.StartedAt.In(time.Local).Format("2006-01-02 15:04"))
+
+	return event
+}
@@ -0,0 +1,409 @@
+package notifications
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+)
+
+// smtpStub is a minimal SMTP server. It offers STARTTLS unless implicit is
+// set, in which case every connection starts with TLS.
+type smtpStub struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	implicit  bool
+	noTLS     bool
+	// replies overrides the reply to a command, e.g. "RCPT" to reject a
+	// recipient.
+	replies map[string]string
+
+	mu       sync.Mutex
+	commands []string
+	auth     []string
+	messages []string
+}
+
+// startSMTPStub listens on localhost and trusts its certificate for the
+// duration of the test.
+func startSMTPStub(t *testing.T, stub *smtpStub) *smtpStub {
+	t.Helper()
+
+	certificate, pool := selfSignedCertificate(t)
+	previous := rootCAs
+	rootCAs = pool
+	stub.tlsConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub.listener = listener
+	t.Cleanup(func() {
+		listener.Close()
+		rootCAs = previous
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+
+	return stub
+}
+
+// email returns a channel config that sends to the stub.
+func (s *smtpStub) email(tlsMode string) appconfig.Email {
+	address := s.listener.Addr().(*net.TCPAddr)
+	return appconfig.Email{
+		Name: "mail",
+		Host: address.IP.String(),
+		Port: address.Port,
+		TLS:  tlsMode,
+		From: "prostic <backup@example.com>",
+		To:   []string{"ops@example.com", "Admin <admin@example.com>"},
+	}
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+
+	secure := false
+	if s.implicit {
+		conn = tls.Server(conn, s.tlsConfig)
+		secure = true
+	}
+	text := textproto.NewConn(conn)
+	if err := text.PrintfLine("220 stub ESMTP"); err != nil {
+		return
+	}
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		s.mu.Lock()
+		s.commands = append(s.commands, verb)
+		s.mu.Unlock()
+
+		if reply, ok := s.replies[verb]; ok {
+			_ = text.PrintfLine("%s", reply)
+			continue
+		}
+
+		switch verb {
+		case "EHLO":
+			extensions := []string{"stub"}
+			if !secure && !s.noTLS {
+				extensions = append(extensions, "STARTTLS")
+			}
+			extensions = append(extensions, "AUTH PLAIN")
+			for i, extension := range extensions {
+				separator := "-"
+				if i == len(extensions)-1 {
+					separator = " "
+				}
+				_ = text.PrintfLine("250%s%s", separator, extension)
+			}
+		case "STARTTLS":
+			_ = text.PrintfLine("220 ready")
+			conn = tls.Server(conn, s.tlsConfig)
+			text = textproto.NewConn(conn)
+			secure = true
+		case "AUTH":
+			_, encoded, _ := strings.Cut(argument, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			s.mu.Lock()
+			s.auth = append(s.auth, string(decoded))
+			s.mu.Unlock()
+			_ = text.PrintfLine("235 authenticated")
+		case "MAIL", "RCPT":
+			_ = text.PrintfLine("250 ok")
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 queued")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpStub) received() (commands []string, auth []string, messages []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.commands), slices.Clone(s.auth), slices.Clone(s.messages)
+}
+
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp stub"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// parseEmail splits a composed message into its decoded subject and the
+// content of its parts by content type.
+func parseEmail(t *testing.T, message string) (string, map[string]string) {
+	t.Helper()
+
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", mediaType)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		// the reader decodes quoted-printable parts
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+
+	return subject, parts
+}
+
+func TestCompose(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour)
+	finishedAt := startedAt.Add(90 * time.Second)
+	run := models.BackupRun{
+		Trigger:        "scheduled",
+		Repository:     "local",
+		Status:         "failed",
+		BackupID:       "20240501-1000",
+		TotalItems:     3,
+		CompletedItems: 2,
+		DataAdded:      3 * 1024 * 1024,
+		StartedAt:      startedAt,
+		FinishedAt:     &finishedAt,
+		Logs:           "Backing up disk\nError: <rbd> unreachable — ünïcode",
+	}
+	channel := emailChannel{config: appconfig.Email{From: "prostic <backup@example.com>", To: []string{"a@example.com", "b@example.com"}}}
+
+	message, err := channel.compose(runReportEvent(run))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Header.Get("To"); got != "a@example.com, b@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if got := parsed.Header.Get("From"); got != "prostic <backup@example.com>" {
+		t.Errorf("From = %q", got)
+	}
+
+	subject, parts := parseEmail(t, string(message))
+	if want := "[prostic] Backup 20240501-1000 failed: 2/3 items"; subject != want {
+		t.Errorf("Subject = %q, want %q", subject, want)
+	}
+
+	text := parts["text/plain"]
+	for _, want := range []string{"Runs: 1, failed: 1, data added: 3.0 MiB", "1m30s", "Error: <rbd> unreachable — ünïcode"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part does not contain %q:\n%s", want, text)
+		}
+	}
+
+	html := parts["text/html"]
+	if !strings.Contains(html, "Error: &lt;rbd&gt; unreachable — ünïcode") {
+		t.Errorf("html part does not contain the escaped log:\n%s", html)
+	}
+	if strings.Contains(html, "<rbd>") {
+		t.Error("html part contains the unescaped log")
+	}
+}
+
+func TestComposeWithoutRuns(t *testing.T) {
+	since := time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local)
+	channel := emailChannel{config: appconfig.Email{From: "backup@example.com", To: []string{"ops@example.com"}}}
+
+	message, err := channel.compose(digestEvent(appconfig.DigestDaily, since, since.Add(24*time.Hour), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, parts := parseEmail(t, string(message))
+	if want := "[prostic] Daily backup report: no backups ran"; subject != want {
+		t.Errorf("Subject = %q, want %q", subject, want)
+	}
+	if !strings.Contains(parts["text/plain"], "No backup runs.") {
+		t.Errorf("text part:\n%s", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], "<p>No backup runs.</p>") {
+		t.Errorf("html part:\n%s", parts["text/html"])
+	}
+}
+
+func TestEmailSend(t *testing.T) {
+	run := models.BackupRun{Trigger: "manual", Status: "success", BackupID: "20240501-1000", StartedAt: time.Now()}
+
+	tests := []struct {
+		name          string
+		implicit      bool
+		noTLS         bool
+		replies       map[string]string
+		tls           string
+		username      string
+		wantStatus    int
+		wantErr       bool
+		wantPermanent bool
+		wantStartTLS  bool
+		wantAuth      string
+		wantMessage   bool
+	}{
+		{name: "starttls with auth", tls: appconfig.EmailTLSStartTLS, username: "user", wantStartTLS: true, wantAuth: "\x00user\x00secret", wantMessage: true},
+		{name: "implicit tls", implicit: true, tls: appconfig.EmailTLSImplicit, wantMessage: true},
+		{name: "starttls not offered", noTLS: true, tls: appconfig.EmailTLSStartTLS, wantErr: true, wantPermanent: true},
+		{name: "rejected login", replies: map[string]string{"AUTH": "535 authentication failed"}, tls: appconfig.EmailTLSStartTLS, username: "user", wantStatus: 535, wantErr: true, wantPermanent: true, wantStartTLS: true},
+		{name: "unknown recipient", replies: map[string]string{"RCPT": "550 no such user"}, tls: appconfig.EmailTLSStartTLS, wantStatus: 550, wantErr: true, wantPermanent: true, wantStartTLS: true},
+		{name: "greylisted", replies: map[string]string{"RCPT": "451 try again later"}, tls: appconfig.EmailTLSStartTLS, wantStatus: 451, wantErr: true, wantStartTLS: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := startSMTPStub(t, &smtpStub{implicit: test.implicit, noTLS: test.noTLS, replies: test.replies})
+			config := stub.email(test.tls)
+			if test.username != "" {
+				config.Username = test.username
+				config.Password = "secret"
+			}
+
+			status, err := emailChannel{config: config}.Send(t.Context(), runReportEvent(run))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Send() error = %v, want error %v", err, test.wantErr)
+			}
+			if status != test.wantStatus {
+				t.Errorf("status = %d, want %d", status, test.wantStatus)
+			}
+			var permanentErr permanentError
+			if errors.As(err, &permanentErr) != test.wantPermanent {
+				t.Errorf("error %v permanent = %v, want %v", err, !test.wantPermanent, test.wantPermanent)
+			}
+
+			commands, auth, messages := stub.received()
+			if slices.Contains(commands, "STARTTLS") != test.wantStartTLS {
+				t.Errorf("commands %v, want STARTTLS %v", commands, test.wantStartTLS)
+			}
+			if test.wantAuth != "" && !slices.Equal(auth, []string{test.wantAuth}) {
+				t.Errorf("auth = %q, want %q", auth, test.wantAuth)
+			}
+			if !test.wantMessage {
+				if len(messages) != 0 {
+					t.Errorf("%d messages delivered, want none", len(messages))
+				}
+				return
+			}
+			if len(messages) != 1 {
+				t.Fatalf("%d messages delivered, want 1", len(messages))
+			}
+			if subject, _ := parseEmail(t, messages[0]); !strings.HasPrefix(subject, "[prostic] Backup 20240501-1000 succeeded") {
+				t.Errorf("Subject = %q", subject)
+			}
+			if rcpts := countCommands(commands, "RCPT"); rcpts != 2 {
+				t.Errorf("%d RCPT commands, want 2", rcpts)
+			}
+		})
+	}
+}
+
+func countCommands(commands []string, verb string) int {
+	count := 0
+	for _, command := range commands {
+		if command == verb {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,141 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"prostic/internal/db/models"
+	backupservice "prostic/internal/service/backups"
+	slaservice "prostic/internal/service/sla"
+)
+
+const (
+	EventRunStarted   = "run_started"
+	EventRunFinished  = "run_finished"
+	EventRunFailed    = "run_failed"
+	EventTaskFinished = "task_finished"
+	EventTaskFailed   = "task_failed"
+	EventSLABreached  = "sla_breached"
+	EventSLARecovered = "sla_recovered"
+	EventRunReport    = "run_report"
+	EventDigest       = "digest"
+	EventTest         = "test"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusInfo    = "info"
+)
+
+// Event is what channels receive and what webhook body templates are
+// executed with.
+type Event struct {
+	Type     string    `json:"type"`
+	Status   string    `json:"status"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+	BackupID string    `json:"backupID,omitempty"`
+	TaskID   uint      `json:"taskID,omitempty"`
+	Purpose  string    `json:"purpose,omitempty"`
+	VMID     *int      `json:"vmid,omitempty"`
+	// CompletedItems and TotalItems are set for backup run events.
+	CompletedItems int `json:"completedItems,omitempty"`
+	TotalItems     int `json:"totalItems,omitempty"`
+
+	// runs are the backup runs an email report covers.
+	runs []models.BackupRun
+}
+
+func (e Event) Failed() bool {
+	return e.Status == StatusFailed
+}
+
+// fromBackupEvent maps the run lifecycle events of the backup observer;
+// progress and item events are not forwarded.
+func fromBackupEvent(event backupservice.Event) (Event, bool) {
+	notification := Event{
+		Time:           time.Now(),
+		BackupID:       event.BackupID,
+		Message:        event.Message,
+		CompletedItems: event.CompletedItems,
+		TotalItems:     event.TotalItems,
+	}
+
+	switch event.Type {
+	case backupservice.EventRunStarted:
+		notification.Type = EventRunStarted
+		notification.Status = StatusInfo
+		notification.Title = "Backup started"
+		if notification.Message == "" {
+			notification.Message = fmt.Sprintf("Backup %s started with %d items", event.BackupID, event.TotalItems)
+		}
+	case backupservice.EventRunDone:
+		notification.Type = EventRunFinished
+		notification.Status = StatusSuccess
+		notification.Title = "Backup finished"
+		if notification.Message == "" {
+			notification.Message = fmt.Sprintf("Backup %s finished, %d of %d items backed up", event.BackupID, event.CompletedItems, event.TotalItems)
+		}
+	case backupservice.EventRunFailed:
+		notification.Type = EventRunFailed
+		notification.Status = StatusFailed
+		notification.Title = "Backup failed"
+		if notification.Message == "" {
+			notification.Message = fmt.Sprintf("Backup %s failed after %d of %d items", event.BackupID, event.CompletedItems, event.TotalItems)
+		}
+	default:
+		return Event{}, false
+	}
+
+	return notification, true
+}
+
+func fromTask(task models.Task, err error) Event {
+	event := Event{
+		Type:    EventTaskFinished,
+		Status:  StatusSuccess,
+		Title:   fmt.Sprintf("Task %s finished", task.Purpose),
+		Message: fmt.Sprintf("Task %d (%s) finished successfully", task.ID, task.Purpose),
+		Time:    time.Now(),
+		TaskID:  task.ID,
+		Purpose: task.Purpose,
+	}
+	if err != nil {
+		event.Type = EventTaskFailed
+		event.Status = StatusFailed
+		event.Title = fmt.Sprintf("Task %s failed", task.Purpose)
+		event.Message = fmt.Sprintf("Task %d (%s) failed: %v", task.ID, task.Purpose, err)
+	}
+
+	return event
+}
+
+func fromAlert(alert slaservice.Alert) Event {
+	vmID := alert.VMID
+	event := Event{
+		Type:    EventSLARecovered,
+		Status:  StatusSuccess,
+		Title:   fmt.Sprintf("Backups of %s are current again", alert.Name),
+		Message: fmt.Sprintf("%s (%d) has a complete backup within its SLA again", alert.Name, alert.VMID),
+		Time:    alert.CheckedAt,
+		VMID:    &vmID,
+	}
+	if !alert.Breached {
+		return event
+	}
+
+	event.Type = EventSLABreached
+	event.Status = StatusFailed
+	event.Title = fmt.Sprintf("Backups of %s are stale", alert.Name)
+	if alert.LastGoodAt == nil {
+		event.Message = fmt.Sprintf("%s (%d) has no complete backup", alert.Name, alert.VMID)
+	} else {
+		age := time.Duration(alert.AgeSeconds) * time.Second
+		maxAge := time.Duration(alert.MaxAgeSeconds) * time.Second
+		event.Message = fmt.Sprintf("The last complete backup of %s (%d) is %s old, the limit is %s", alert.Name, alert.VMID, age.Round(time.Minute), maxAge)
+	}
+
+	return event
+}
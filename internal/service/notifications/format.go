@@ -0,0 +1,158 @@
+package notifications
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"prostic/internal/db/repo"
+)
+
+const (
+	priorityLow = iota
+	priorityNormal
+	priorityHigh
+)
+
+// message is an event prepared for the push and chat formatters.
+type message struct {
+	Title    string
+	Text     string
+	Fields   []messageField
+	Guests   []string
+	Priority int
+	Status   string
+}
+
+type messageField struct {
+	Name  string
+	Value string
+}
+
+// newMessage builds the message of event. Backup run results are summarized
+// with the items recorded for the backup ID.
+func newMessage(event Event) message {
+	msg := message{
+		Title:    event.Title,
+		Text:     event.Message,
+		Priority: priorityNormal,
+		Status:   event.Status,
+	}
+	switch event.Status {
+	case StatusFailed:
+		msg.Priority = priorityHigh
+	case StatusInfo:
+		msg.Priority = priorityLow
+	}
+
+	if event.TaskID != 0 {
+		msg.Fields = append(msg.Fields, messageField{"Task", fmt.Sprintf("%d (%s)", event.TaskID, event.Purpose)})
+	}
+	if event.BackupID == "" {
+		return msg
+	}
+
+	msg.Fields = append(msg.Fields, messageField{"Backup ID", event.BackupID})
+	if event.TotalItems > 0 {
+		msg.Fields = append(msg.Fields, messageField{"Items", fmt.Sprintf("%d/%d", event.CompletedItems, event.TotalItems)})
+	}
+	if event.Type != EventRunFinished && event.Type != EventRunFailed {
+		return msg
+	}
+
+	items, err := repo.ListBackupItemsForBackupID(event.BackupID)
+	if err != nil || len(items) == 0 {
+		return msg
+	}
+
+	var dataAdded int64
+	var order []int
+	guests := make(map[int]*guestResult)
+	for _, item := range items {
+		dataAdded += item.DataAdded
+		guest := guests[item.VMID]
+		if guest == nil {
+			guest = &guestResult{VMID: item.VMID, Name: item.VMName}
+			guests[item.VMID] = guest
+			order = append(order, item.VMID)
+		}
+		guest.Items++
+		guest.DataAdded += item.DataAdded
+	}
+
+	msg.Fields = append(msg.Fields,
+		messageField{"Data added", formatBytes(dataAdded)},
+		messageField{"Duration", event.Time.Sub(items[0].StartedAt).Round(time.Second).String()},
+	)
+	for _, vmID := range order {
+		guest := guests[vmID]
+		msg.Guests = append(msg.Guests, fmt.Sprintf("%s (%d): %d items, %s added", guest.Name, guest.VMID, guest.Items, formatBytes(guest.DataAdded)))
+	}
+
+	return msg
+}
+
+// markdown renders the body without the title, for services that show the
+// title separately.
+func (m message) markdown() string {
+	var out strings.Builder
+	out.WriteString(m.Text)
+	if len(m.Fields) > 0 {
+		out.WriteString("\n")
+	}
+	for _, field := range m.Fields {
+		out.WriteString(fmt.Sprintf("\n**%s:** %s", field.Name, field.Value))
+	}
+	if len(m.Guests) > 0 {
+		out.WriteString("\n")
+	}
+	for _, guest := range m.Guests {
+		out.WriteString("\n- " + guest)
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+// slack renders title and body as Slack mrkdwn, which uses single asterisks
+// for bold text.
+func (m message) slack() string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("%s *%s*\n%s", m.icon(), m.Title, m.Text))
+	for _, field := range m.Fields {
+		out.WriteString(fmt.Sprintf("\n*%s:* %s", field.Name, field.Value))
+	}
+	for _, guest := range m.Guests {
+		out.WriteString("\n• " + guest)
+	}
+
+	return out.String()
+}
+
+func (m message) html() string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("<strong>%s %s</strong><br>%s", m.icon(), html.EscapeString(m.Title), html.EscapeString(m.Text)))
+	for _, field := range m.Fields {
+		out.WriteString(fmt.Sprintf("<br><strong>%s:</strong> %s", html.EscapeString(field.Name), html.EscapeString(field.Value)))
+	}
+	if len(m.Guests) > 0 {
+		out.WriteString("<ul>")
+		for _, guest := range m.Guests {
+			out.WriteString("<li>" + html.EscapeString(guest) + "</li>")
+		}
+		out.WriteString("</ul>")
+	}
+
+	return out.String()
+}
+
+func (m message) icon() string {
+	switch m.Status {
+	case StatusFailed:
+		return "🚨"
+	case StatusSuccess:
+		return "✅"
+	}
+
+	return "ℹ️"
+}
@@ -0,0 +1,42 @@
+package notifications
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "prostic-notifications-test")
+	if err != nil {
+		panic(err)
+	}
+	// deliveries are recorded in the database
+	os.Setenv("PROSTIC_DB_PATH", filepath.Join(dir, "test.db"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// recordSleeps replaces the delay between attempts for the duration of a
+// test and returns the delays that were requested.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+
+	var delays []time.Duration
+	previous := sleep
+	sleep = func(delay time.Duration) {
+		delays = append(delays, delay)
+	}
+	t.Cleanup(func() {
+		sleep = previous
+	})
+
+	return &delays
+}
+
+func intPointer(value int) *int {
+	return &value
+}
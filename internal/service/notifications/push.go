@@ -0,0 +1,168 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	appconfig "prostic/internal/config"
+)
+
+// ntfyPriorities and gotifyPriorities map the message priority to the scale
+// of the service.
+var (
+	ntfyPriorities   = map[int]int{priorityLow: 2, priorityNormal: 3, priorityHigh: 4}
+	gotifyPriorities = map[int]int{priorityLow: 2, priorityNormal: 5, priorityHigh: 8}
+	ntfyTags         = map[string]string{StatusFailed: "rotating_light", StatusSuccess: "white_check_mark", StatusInfo: "information_source"}
+)
+
+// pushChannel formats events for ntfy, Gotify, Slack-compatible and Matrix
+// webhooks, so no body template is needed.
+type pushChannel struct {
+	config appconfig.Push
+}
+
+func (p pushChannel) Name() string {
+	return p.config.Name
+}
+
+func (p pushChannel) Kind() string {
+	return p.config.Service
+}
+
+func (p pushChannel) Accepts(event Event) bool {
+	if event.Type == EventRunReport || event.Type == EventDigest {
+		return false
+	}
+
+	return accepts(p.config.Filter, p.config.Events, event)
+}
+
+func (p pushChannel) Retries() int {
+	return p.config.RetryCount()
+}
+
+func (p pushChannel) Send(ctx context.Context, event Event) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout())
+	defer cancel()
+
+	msg := newMessage(event)
+	var request *http.Request
+	var err error
+	switch p.config.Service {
+	case appconfig.PushNtfy:
+		request, err = p.ntfyRequest(ctx, msg)
+	case appconfig.PushGotify:
+		request, err = p.gotifyRequest(ctx, msg)
+	case appconfig.PushSlack:
+		request, err = jsonRequest(ctx, http.MethodPost, p.config.URL, map[string]interface{}{
+			"text":   msg.slack(),
+			"mrkdwn": true,
+		})
+	case appconfig.PushMatrix:
+		request, err = p.matrixRequest(ctx, msg, event)
+	default:
+		err = fmt.Errorf("unknown push service %q", p.config.Service)
+	}
+	if err != nil {
+		return 0, permanent(err)
+	}
+
+	request.Header.Set("User-Agent", "prostic")
+	request.Header.Set("X-Prostic-Event", event.Type)
+	return doRequest(request)
+}
+
+// ntfyRequest publishes as JSON to the server root, which unlike the header
+// API allows UTF-8 titles.
+func (p pushChannel) ntfyRequest(ctx context.Context, msg message) (*http.Request, error) {
+	topicURL, err := url.Parse(p.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	topic := path.Base(topicURL.Path)
+	topicURL.Path = path.Dir(topicURL.Path)
+
+	request, err := jsonRequest(ctx, http.MethodPost, topicURL.String(), map[string]interface{}{
+		"topic":    topic,
+		"title":    msg.Title,
+		"message":  msg.markdown(),
+		"priority": ntfyPriorities[msg.Priority],
+		"tags":     []string{ntfyTags[msg.Status]},
+		"markdown": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.config.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+p.config.Token)
+	}
+
+	return request, nil
+}
+
+func (p pushChannel) gotifyRequest(ctx context.Context, msg message) (*http.Request, error) {
+	request, err := jsonRequest(ctx, http.MethodPost, strings.TrimRight(p.config.URL, "/")+"/message", map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.markdown(),
+		"priority": gotifyPriorities[msg.Priority],
+		"extras": map[string]interface{}{
+			"client::display": map[string]string{"contentType": "text/markdown"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Gotify-Key", p.config.Token)
+
+	return request, nil
+}
+
+// matrixRequest sends a notice through the client API when a room is
+// configured, otherwise it posts to a hookshot generic webhook. The
+// transaction ID is derived from the event so retries are not duplicated.
+func (p pushChannel) matrixRequest(ctx context.Context, msg message, event Event) (*http.Request, error) {
+	text := fmt.Sprintf("**%s**\n%s", msg.Title, msg.markdown())
+	if p.config.Room == "" {
+		return jsonRequest(ctx, http.MethodPost, p.config.URL, map[string]string{
+			"text": text,
+			"html": msg.html(),
+		})
+	}
+
+	transactionID := "prostic-" + event.Type + "-" + strconv.FormatInt(event.Time.UnixNano(), 10)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", strings.TrimRight(p.config.URL, "/"), url.PathEscape(p.config.Room), transactionID)
+	request, err := jsonRequest(ctx, http.MethodPut, endpoint, map[string]string{
+		"msgtype":        "m.notice",
+		"body":           text,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": msg.html(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+p.config.Token)
+
+	return request, nil
+}
+
+func jsonRequest(ctx context.Context, method string, target string, payload interface{}) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return request, nil
+}
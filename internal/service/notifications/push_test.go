@@ -0,0 +1,162 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	appconfig "prostic/internal/config"
+)
+
+func TestPushRequests(t *testing.T) {
+	event := testEvent
+	event.TaskID = 7
+	event.Purpose = "backup"
+
+	const (
+		markdown = "restic exited with status 1\n\n**Task:** 7 (backup)"
+		html     = "<strong>🚨 Backup failed</strong><br>restic exited with status 1<br><strong>Task:</strong> 7 (backup)"
+	)
+
+	tests := []struct {
+		name       string
+		config     appconfig.Push
+		wantMethod string
+		wantPath   string
+		wantHeader map[string]string
+		wantBody   map[string]interface{}
+	}{
+		{
+			name:       "ntfy",
+			config:     appconfig.Push{Service: appconfig.PushNtfy, URL: "/alerts/backups", Token: "tk_ntfy"},
+			wantMethod: http.MethodPost,
+			wantPath:   "/alerts",
+			wantHeader: map[string]string{"Authorization": "Bearer tk_ntfy"},
+			wantBody: map[string]interface{}{
+				"topic":    "backups",
+				"title":    "Backup failed",
+				"message":  markdown,
+				"priority": float64(4),
+				"tags":     []interface{}{"rotating_light"},
+				"markdown": true,
+			},
+		},
+		{
+			name:       "ntfy without token",
+			config:     appconfig.Push{Service: appconfig.PushNtfy, URL: "/backups"},
+			wantMethod: http.MethodPost,
+			wantPath:   "/",
+			wantHeader: map[string]string{"Authorization": ""},
+			wantBody: map[string]interface{}{
+				"topic":    "backups",
+				"title":    "Backup failed",
+				"message":  markdown,
+				"priority": float64(4),
+				"tags":     []interface{}{"rotating_light"},
+				"markdown": true,
+			},
+		},
+		{
+			name:       "gotify",
+			config:     appconfig.Push{Service: appconfig.PushGotify, URL: "/gotify/", Token: "app-token"},
+			wantMethod: http.MethodPost,
+			wantPath:   "/gotify/message",
+			wantHeader: map[string]string{"X-Gotify-Key": "app-token"},
+			wantBody: map[string]interface{}{
+				"title":    "Backup failed",
+				"message":  markdown,
+				"priority": float64(8),
+				"extras": map[string]interface{}{
+					"client::display": map[string]interface{}{"contentType": "text/markdown"},
+				},
+			},
+		},
+		{
+			name:       "slack",
+			config:     appconfig.Push{Service: appconfig.PushSlack, URL: "/services/T000/B000/XXXX"},
+			wantMethod: http.MethodPost,
+			wantPath:   "/services/T000/B000/XXXX",
+			wantBody: map[string]interface{}{
+				"text":   "🚨 *Backup failed*\nrestic exited with status 1\n*Task:* 7 (backup)",
+				"mrkdwn": true,
+			},
+		},
+		{
+			name:       "matrix room",
+			config:     appconfig.Push{Service: appconfig.PushMatrix, URL: "/", Room: "!room:example.org", Token: "syt_token"},
+			wantMethod: http.MethodPut,
+			wantPath:   "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/prostic-run_failed-1714557600000000000",
+			wantHeader: map[string]string{"Authorization": "Bearer syt_token"},
+			wantBody: map[string]interface{}{
+				"msgtype":        "m.notice",
+				"body":           "**Backup failed**\n" + markdown,
+				"format":         "org.matrix.custom.html",
+				"formatted_body": html,
+			},
+		},
+		{
+			name:       "matrix hookshot",
+			config:     appconfig.Push{Service: appconfig.PushMatrix, URL: "/webhook/abc"},
+			wantMethod: http.MethodPost,
+			wantPath:   "/webhook/abc",
+			wantBody: map[string]interface{}{
+				"text": "**Backup failed**\n" + markdown,
+				"html": html,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := stubServer(t, http.StatusOK)
+			config := test.config
+			config.Name = test.name
+			config.URL = server.URL + config.URL
+			channel := pushChannel{config: config}
+
+			status, err := channel.Send(t.Context(), event)
+			if err != nil || status != http.StatusOK {
+				t.Fatalf("Send() = %d, %v", status, err)
+			}
+
+			request := requests()[0]
+			if request.method != test.wantMethod {
+				t.Errorf("method = %s, want %s", request.method, test.wantMethod)
+			}
+			if request.path != test.wantPath {
+				t.Errorf("path = %s, want %s", request.path, test.wantPath)
+			}
+			if request.header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q", request.header.Get("Content-Type"))
+			}
+			if request.header.Get("X-Prostic-Event") != EventRunFailed {
+				t.Errorf("X-Prostic-Event = %q", request.header.Get("X-Prostic-Event"))
+			}
+			for name, want := range test.wantHeader {
+				if got := request.header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(request.body, &body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if !reflect.DeepEqual(body, test.wantBody) {
+				t.Errorf("body = %#v\nwant %#v", body, test.wantBody)
+			}
+		})
+	}
+}
+
+func TestPushUnknownServiceIsPermanent(t *testing.T) {
+	channel := pushChannel{config: appconfig.Push{Name: "push", Service: "pager", URL: "http://localhost"}}
+
+	_, err := channel.Send(t.Context(), testEvent)
+	var permanentErr permanentError
+	if !errors.As(err, &permanentErr) {
+		t.Errorf("Send() error = %v, want a permanent error", err)
+	}
+}
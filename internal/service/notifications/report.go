@@ -0,0 +1,261 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	backupservice "prostic/internal/service/backups"
+)
+
+// reportLogLines is how much of the log of a failed run is quoted in a
+// report.
+const reportLogLines = 15
+
+type report struct {
+	Title        string
+	Subtitle     string
+	Runs         []runResult
+	Failed       int
+	DataAdded    int64
+	Guests       []guestResult
+	Repositories []repositorySize
+	Generated    time.Time
+}
+
+type runResult struct {
+	Run      models.BackupRun
+	Failed   bool
+	Duration time.Duration
+	LogTail  string
+}
+
+type guestResult struct {
+	VMID       int
+	Name       string
+	Items      int
+	DataAdded  int64
+	Duration   time.Duration
+	LastBackup time.Time
+}
+
+type repositorySize struct {
+	Repository       string
+	TotalSize        int64
+	CompressionRatio float64
+	SnapshotsCount   int64
+	RefreshedAt      time.Time
+}
+
+// buildReport collects the per-guest results and repository sizes of runs.
+func buildReport(title string, subtitle string, runs []models.BackupRun) (*report, error) {
+	result := &report{
+		Title:     title,
+		Subtitle:  subtitle,
+		Generated: time.Now(),
+	}
+
+	guests := make(map[int]*guestResult)
+	repositories := make(map[string]bool)
+	for _, run := range runs {
+		entry := runResult{
+			Run:     run,
+			Failed:  run.Status == backupservice.StatusFailed,
+			LogTail: logTail(run.Logs, reportLogLines),
+		}
+		if run.FinishedAt != nil {
+			entry.Duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Second)
+		}
+		if entry.Failed {
+			result.Failed++
+		}
+		result.DataAdded += run.DataAdded
+		result.Runs = append(result.Runs, entry)
+		if run.Repository != "" {
+			repositories[run.Repository] = true
+		}
+
+		items, err := repo.ListBackupItemsForRun(run.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			repositories[item.Repository] = true
+			guest := guests[item.VMID]
+			if guest == nil {
+				guest = &guestResult{VMID: item.VMID}
+				guests[item.VMID] = guest
+			}
+			guest.Name = item.VMName
+			guest.Items++
+			guest.DataAdded += item.DataAdded
+			guest.Duration += time.Duration(item.DurationSeconds * float64(time.Second)).Round(time.Second)
+			if item.FinishedAt.After(guest.LastBackup) {
+				guest.LastBackup = item.FinishedAt
+			}
+		}
+	}
+
+	for _, guest := range guests {
+		result.Guests = append(result.Guests, *guest)
+	}
+	sort.Slice(result.Guests, func(i, j int) bool {
+		return result.Guests[i].VMID < result.Guests[j].VMID
+	})
+
+	names := make([]string, 0, len(repositories))
+	for name := range repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stat, err := repo.GetLatestRepoStat(name)
+		if err != nil {
+			return nil, err
+		}
+		if stat == nil {
+			continue
+		}
+		result.Repositories = append(result.Repositories, repositorySize{
+			Repository:       name,
+			TotalSize:        stat.TotalSize,
+			CompressionRatio: stat.CompressionRatio,
+			SnapshotsCount:   stat.SnapshotsCount,
+			RefreshedAt:      stat.LastRefreshedAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (r *report) renderText() (string, error) {
+	var out bytes.Buffer
+	if err := textReport.Execute(&out, r); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func (r *report) renderHTML() (string, error) {
+	var out bytes.Buffer
+	if err := htmlReport.Execute(&out, r); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func logTail(logs string, lines int) string {
+	all := strings.Split(strings.TrimSpace(logs), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+
+	return strings.Join(all, "\n")
+}
+
+// formatBytes formats size with binary units, e.g. 1.5 GiB.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size)
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	index := -1
+	for value >= unit && index < len(units)-1 {
+		value /= unit
+		index++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[index])
+}
+
+var reportFuncs = map[string]interface{}{
+	"bytes": formatBytes,
+	"time": func(t time.Time) string {
+		return t.In(time.Local).Format("2006-01-02 15:04")
+	},
+}
+
+var textReport = template.Must(template.New("text").Funcs(reportFuncs).Parse(`{{.Title}}
+{{if .Subtitle}}{{.Subtitle}}
+{{end}}
+{{- if not .Runs}}
+No backup runs.
+{{else}}
+Runs: {{len .Runs}}, failed: {{.Failed}}, data added: {{bytes .DataAdded}}
+
+{{range .Runs -}}
+{{time .Run.StartedAt}}  {{.Run.Status}}  {{.Run.BackupID}}  {{.Run.CompletedItems}}/{{.Run.TotalItems}} items  {{.Duration}}  {{bytes .Run.DataAdded}} added
+{{if and .Failed .LogTail}}
+{{.LogTail}}
+
+{{end}}
+{{- end}}
+{{- end}}
+{{- if .Guests}}
+Guests
+{{range .Guests -}}
+{{.VMID}} {{.Name}}: {{.Items}} items, {{bytes .DataAdded}} added in {{.Duration}}, last backup {{time .LastBackup}}
+{{end}}
+{{- end}}
+{{- if .Repositories}}
+Repositories
+{{range .Repositories -}}
+{{.Repository}}: {{bytes .TotalSize}}, {{.SnapshotsCount}} snapshots, compression {{printf "%.2f" .CompressionRatio}}x
+{{end}}
+{{- end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; font-size: 14px; color: #222;">
+<h2 style="margin-bottom: 4px;">{{.Title}}</h2>
+{{if .Subtitle}}<p style="margin-top: 0; color: #666;">{{.Subtitle}}</p>{{end}}
+{{if not .Runs}}
+<p>No backup runs.</p>
+{{else}}
+<p>Runs: {{len .Runs}}, failed: {{.Failed}}, data added: {{bytes .DataAdded}}</p>
+<table cellpadding="6" cellspacing="0" border="1" style="border-collapse: collapse; border-color: #ddd;">
+<tr style="background: #f4f4f4;"><th>Started</th><th>Status</th><th>Backup ID</th><th>Items</th><th>Duration</th><th>Data added</th></tr>
+{{range .Runs}}
+<tr>
+<td>{{time .Run.StartedAt}}</td>
+<td style="color: {{if .Failed}}#c62828{{else}}#2e7d32{{end}}; font-weight: bold;">{{.Run.Status}}</td>
+<td>{{.Run.BackupID}}</td>
+<td>{{.Run.CompletedItems}}/{{.Run.TotalItems}}</td>
+<td>{{.Duration}}</td>
+<td>{{bytes .Run.DataAdded}}</td>
+</tr>
+{{if and .Failed .LogTail}}<tr><td colspan="6"><pre style="margin: 0; white-space: pre-wrap;">{{.LogTail}}</pre></td></tr>{{end}}
+{{end}}
+</table>
+{{end}}
+{{if .Guests}}
+<h3>Guests</h3>
+<table cellpadding="6" cellspacing="0" border="1" style="border-collapse: collapse; border-color: #ddd;">
+<tr style="background: #f4f4f4;"><th>VMID</th><th>Name</th><th>Items</th><th>Data added</th><th>Duration</th><th>Last backup</th></tr>
+{{range .Guests}}<tr><td>{{.VMID}}</td><td>{{.Name}}</td><td>{{.Items}}</td><td>{{bytes .DataAdded}}</td><td>{{.Duration}}</td><td>{{time .LastBackup}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Repositories}}
+<h3>Repositories</h3>
+<table cellpadding="6" cellspacing="0" border="1" style="border-collapse: collapse; border-color: #ddd;">
+<tr style="background: #f4f4f4;"><th>Repository</th><th>Size</th><th>Snapshots</th><th>Compression</th><th>Updated</th></tr>
+{{range .Repositories}}<tr><td>{{.Repository}}</td><td>{{bytes .TotalSize}}</td><td>{{.SnapshotsCount}}</td><td>{{printf "%.2f" .CompressionRatio}}x</td><td>{{time .RefreshedAt}}</td></tr>
+{{end}}
+</table>
+{{end}}
+<p style="color: #999; font-size: 12px;">Sent by prostic at {{time .Generated}}</p>
+</body>
+</html>
+`))
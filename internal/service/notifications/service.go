@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	backupservice "prostic/internal/service/backups"
+	slaservice "prostic/internal/service/sla"
+	taskservice "prostic/internal/service/tasks"
+	"prostic/internal/util"
+)
+
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSuccess = "success"
+	DeliveryStatusFailed  = "failed"
+)
+
+// maxRetryDelay caps the exponential backoff between delivery attempts.
+const maxRetryDelay = time.Minute
+
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Channel delivers events to one configured destination.
+type Channel interface {
+	Name() string
+	Kind() string
+	Accepts(event Event) bool
+	Retries() int
+	// Send delivers event once and returns the response status code if the
+	// destination has one.
+	Send(ctx context.Context, event Event) (int, error)
+}
+
+type ChannelInfo struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// permanentError marks failures that retrying cannot fix, like a rejected
+// request or a broken template.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+var logger = util.GroupLogger("notifications")
+
+// sleep waits between delivery attempts; tests replace it.
+var sleep = time.Sleep
+
+// Start subscribes to backup runs, background tasks and SLA changes.
+func Start() {
+	backupservice.OnRunFinished(AfterBackup)
+	backupservice.AddObserver(backupservice.ObserverFunc(func(event backupservice.Event) {
+		if notification, ok := fromBackupEvent(event); ok {
+			Publish(notification)
+		}
+	}))
+	taskservice.OnTaskFinished(func(task models.Task, err error) {
+		Publish(fromTask(task, err))
+	})
+	slaservice.OnChange(func(alert slaservice.Alert) {
+		Publish(fromAlert(alert))
+	})
+}
+
+// Publish delivers event in the background to every channel that accepts it.
+func Publish(event Event) {
+	for _, channel := range channels() {
+		if !channel.Accepts(event) {
+			continue
+		}
+
+		go func(channel Channel) {
+			_, _ = deliver(channel, event)
+		}(channel)
+	}
+}
+
+// Test sends a test event to the named channel and waits for the result.
+func Test(name string) (*models.NotificationDelivery, error) {
+	for _, channel := range channels() {
+		if channel.Name() != name {
+			continue
+		}
+
+		return deliver(channel, Event{
+			Type:    EventTest,
+			Status:  StatusInfo,
+			Title:   "Test notification",
+			Message: "This is a test notification from prostic.",
+			Time:    time.Now(),
+		})
+	}
+
+	return nil, ErrUnknownChannel
+}
+
+func ListChannels() []ChannelInfo {
+	infos := make([]ChannelInfo, 0)
+	for _, channel := range channels() {
+		infos = append(infos, ChannelInfo{Name: channel.Name(), Kind: channel.Kind()})
+	}
+
+	return infos
+}
+
+func ListDeliveries(channel string, limit int) ([]models.NotificationDelivery, error) {
+	return repo.ListNotificationDeliveries(channel, limit)
+}
+
+func channels() []Channel {
+	cfg := appconfig.Get()
+	if cfg == nil {
+		return nil
+	}
+
+	list := make([]Channel, 0, len(cfg.Notifications.Webhooks)+len(cfg.Notifications.Email)+len(cfg.Notifications.Push))
+	for _, webhook := range cfg.Notifications.Webhooks {
+		list = append(list, webhookChannel{config: webhook})
+	}
+	for _, email := range cfg.Notifications.Email {
+		list = append(list, emailChannel{config: email})
+	}
+	for _, push := range cfg.Notifications.Push {
+		list = append(list, pushChannel{config: push})
+	}
+
+	return list
+}
+
+// deliver sends event with retries and records every delivery in the log.
+func deliver(channel Channel, event Event) (*models.NotificationDelivery, error) {
+	delivery := &models.NotificationDelivery{
+		Channel:     channel.Name(),
+		Kind:        channel.Kind(),
+		EventType:   event.Type,
+		EventStatus: event.Status,
+		Title:       event.Title,
+		Status:      DeliveryStatusPending,
+		StartedAt:   time.Now(),
+	}
+	if err := repo.CreateNotificationDelivery(delivery); err != nil {
+		logger.Warnf("Could not record notification for %s: %v", channel.Name(), err)
+	}
+
+	var sendErr error
+	for attempt := 0; attempt <= channel.Retries(); attempt++ {
+		if attempt > 0 {
+			sleep(retryDelay(attempt))
+		}
+
+		delivery.Attempts = attempt + 1
+		delivery.StatusCode, sendErr = channel.Send(context.Background(), event)
+		var permanentErr permanentError
+		if sendErr == nil || errors.As(sendErr, &permanentErr) {
+			break
+		}
+	}
+
+	finishedAt := time.Now()
+	delivery.FinishedAt = &finishedAt
+	delivery.Status = DeliveryStatusSuccess
+	if sendErr != nil {
+		delivery.Status = DeliveryStatusFailed
+		delivery.Error = util.Redact(sendErr.Error())
+		logger.Warnf("Notification %s to %s failed after %d attempt(s): %s", event.Type, channel.Name(), delivery.Attempts, delivery.Error)
+	}
+
+	if delivery.ID != 0 {
+		if err := repo.UpdateNotificationDelivery(delivery); err != nil {
+			logger.Warnf("Could not record notification for %s: %v", channel.Name(), err)
+		}
+	}
+
+	return delivery, sendErr
+}
+
+func retryDelay(attempt int) time.Duration {
+	delay := time.Second << (attempt - 1)
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,126 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"text/template"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/util"
+)
+
+const KindWebhook = "webhook"
+
+// maxResponseBody limits how much of a response is read before the
+// connection is reused.
+const maxResponseBody = 64 << 10
+
+type webhookChannel struct {
+	config appconfig.Webhook
+}
+
+func (w webhookChannel) Name() string {
+	return w.config.Name
+}
+
+func (w webhookChannel) Kind() string {
+	return KindWebhook
+}
+
+func (w webhookChannel) Accepts(event Event) bool {
+	return accepts(w.config.Filter, w.config.Events, event)
+}
+
+func (w webhookChannel) Retries() int {
+	return w.config.RetryCount()
+}
+
+func (w webhookChannel) Send(ctx context.Context, event Event) (int, error) {
+	body, err := w.render(event)
+	if err != nil {
+		return 0, permanent(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.config.RequestTimeout())
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, w.config.Method, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanent(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "prostic")
+	request.Header.Set("X-Prostic-Event", event.Type)
+	for key, value := range w.config.Headers {
+		request.Header.Set(key, value)
+	}
+	if w.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.config.Secret))
+		mac.Write(body)
+		request.Header.Set("X-Prostic-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	return doRequest(request)
+}
+
+// render executes the body template, or encodes the event as JSON when no
+// template is configured.
+func (w webhookChannel) render(event Event) ([]byte, error) {
+	if w.config.Body == "" {
+		return json.Marshal(event)
+	}
+
+	tmpl, err := template.New(w.config.Name).Funcs(util.TemplateFuncs).Parse(w.config.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, event); err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+func doRequest(request *http.Request) (int, error) {
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseBody))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response.StatusCode, nil
+	}
+
+	err = fmt.Errorf("unexpected response status %s", response.Status)
+	if response.StatusCode < 500 && response.StatusCode != http.StatusTooManyRequests {
+		return response.StatusCode, permanent(err)
+	}
+
+	return response.StatusCode, err
+}
+
+func accepts(filter string, events []string, event Event) bool {
+	if event.Type == EventTest {
+		return true
+	}
+	if len(events) > 0 && !slices.Contains(events, event.Type) {
+		return false
+	}
+	if filter == appconfig.NotifyAlways {
+		return true
+	}
+
+	return event.Failed()
+}
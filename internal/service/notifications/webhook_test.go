@@ -0,0 +1,232 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+)
+
+var testEvent = Event{
+	Type:    EventRunFailed,
+	Status:  StatusFailed,
+	Title:   "Backup failed",
+	Message: "restic exited with status 1",
+	Time:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+}
+
+type capturedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// stubServer answers requests with the given status codes in turn, repeating
+// the last one, and records every request.
+func stubServer(t *testing.T, statuses ...int) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, capturedRequest{method: r.Method, path: r.URL.Path, header: r.Header.Clone(), body: body})
+		status := statuses[min(len(requests), len(statuses))-1]
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(requests)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "signed", secret: "webhook-secret"},
+		{name: "unsigned"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := stubServer(t, http.StatusNoContent)
+			channel := webhookChannel{config: appconfig.Webhook{
+				Name:    "hook",
+				URL:     server.URL,
+				Method:  http.MethodPost,
+				Headers: map[string]string{"X-Custom": "value"},
+				Secret:  test.secret,
+			}}
+
+			status, err := channel.Send(t.Context(), testEvent)
+			if err != nil || status != http.StatusNoContent {
+				t.Fatalf("Send() = %d, %v", status, err)
+			}
+
+			request := requests()[0]
+			if request.header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q", request.header.Get("Content-Type"))
+			}
+			if request.header.Get("X-Prostic-Event") != EventRunFailed {
+				t.Errorf("X-Prostic-Event = %q", request.header.Get("X-Prostic-Event"))
+			}
+			if request.header.Get("X-Custom") != "value" {
+				t.Errorf("X-Custom = %q", request.header.Get("X-Custom"))
+			}
+
+			signature := request.header.Get("X-Prostic-Signature")
+			if test.secret == "" {
+				if signature != "" {
+					t.Errorf("unsigned request has signature %q", signature)
+				}
+				return
+			}
+			mac := hmac.New(sha256.New, []byte(test.secret))
+			mac.Write(request.body)
+			if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+				t.Errorf("X-Prostic-Signature = %q, want %q", signature, want)
+			}
+		})
+	}
+}
+
+func TestWebhookBodyTemplate(t *testing.T) {
+	server, requests := stubServer(t, http.StatusOK)
+	channel := webhookChannel{config: appconfig.Webhook{
+		Name:   "hook",
+		URL:    server.URL,
+		Method: http.MethodPut,
+		Body:   `{"text": "{{ .Title }}: {{ .Message }}"}`,
+	}}
+
+	if _, err := channel.Send(t.Context(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	request := requests()[0]
+	if request.method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", request.method)
+	}
+	if want := `{"text": "Backup failed: restic exited with status 1"}`; string(request.body) != want {
+		t.Errorf("body = %s, want %s", request.body, want)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		retries      int
+		wantAttempts int
+		wantStatus   string
+		wantDelays   []time.Duration
+	}{
+		{
+			name:         "success",
+			statuses:     []int{http.StatusOK},
+			retries:      3,
+			wantAttempts: 1,
+			wantStatus:   DeliveryStatusSuccess,
+		},
+		{
+			name:         "server errors then success",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			retries:      3,
+			wantAttempts: 3,
+			wantStatus:   DeliveryStatusSuccess,
+			wantDelays:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "server errors until retries are used up",
+			statuses:     []int{http.StatusInternalServerError},
+			retries:      2,
+			wantAttempts: 3,
+			wantStatus:   DeliveryStatusFailed,
+			wantDelays:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "too many requests is retried",
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			retries:      1,
+			wantAttempts: 2,
+			wantStatus:   DeliveryStatusSuccess,
+			wantDelays:   []time.Duration{time.Second},
+		},
+		{
+			name:         "client error is permanent",
+			statuses:     []int{http.StatusBadRequest, http.StatusOK},
+			retries:      3,
+			wantAttempts: 1,
+			wantStatus:   DeliveryStatusFailed,
+		},
+		{
+			name:         "unauthorized is permanent",
+			statuses:     []int{http.StatusUnauthorized},
+			retries:      3,
+			wantAttempts: 1,
+			wantStatus:   DeliveryStatusFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delays := recordSleeps(t)
+			server, requests := stubServer(t, test.statuses...)
+			channel := webhookChannel{config: appconfig.Webhook{
+				Name:    "hook",
+				URL:     server.URL,
+				Method:  http.MethodPost,
+				Retries: intPointer(test.retries),
+			}}
+
+			delivery, err := deliver(channel, testEvent)
+			if (err == nil) != (test.wantStatus == DeliveryStatusSuccess) {
+				t.Errorf("deliver() error = %v", err)
+			}
+			if delivery.Status != test.wantStatus {
+				t.Errorf("status = %s, want %s", delivery.Status, test.wantStatus)
+			}
+			if delivery.Attempts != test.wantAttempts || len(requests()) != test.wantAttempts {
+				t.Errorf("attempts = %d with %d requests, want %d", delivery.Attempts, len(requests()), test.wantAttempts)
+			}
+			if !slices.Equal(*delays, test.wantDelays) {
+				t.Errorf("delays = %v, want %v", *delays, test.wantDelays)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 6, want: 32 * time.Second},
+		{attempt: 7, want: maxRetryDelay},
+		{attempt: 20, want: maxRetryDelay},
+	}
+
+	for _, test := range tests {
+		if got := retryDelay(test.attempt); got != test.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", test.attempt, got, test.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package prune
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -10,13 +11,19 @@ import (
 	"prostic/internal/restic"
 	cacheservice "prostic/internal/service/cache"
 	snapshotservice "prostic/internal/service/snapshots"
+	taskservice "prostic/internal/service/tasks"
 )
 
 const TaskPurposePruneNotInConfig = "prune_not_in_config"
 const TaskPurposeDeleteSnapshot = "delete_snapshot"
 const TaskPurposeDeleteBackupID = "delete_backup_id"
 
+// pruneTimeout replaces restic.timeout since pruning rewrites packs and can
+// take hours on large repositories.
+const pruneTimeout = 24 * time.Hour
+
 type SnapshotCandidate struct {
+	Repository   string `json:"repository"`
 	SnapshotID   string `json:"snapshotID"`
 	Time         string `json:"time"`
 	BackupID     string `json:"backupID"`
@@ -27,12 +34,12 @@ type SnapshotCandidate struct {
 	SrcFile      string `json:"srcFile"`
 }
 
-func PreviewNotInConfig() ([]SnapshotCandidate, error) {
+func PreviewNotInConfig(repository string) ([]SnapshotCandidate, error) {
 	if _, err := snapshotservice.RefreshSnapshotCache(); err != nil {
 		return nil, err
 	}
 
-	snapshots, err := repo.ListSnapshots()
+	snapshots, err := repo.ListSnapshots(repository)
 	if err != nil {
 		return nil, err
 	}
@@ -44,6 +51,7 @@ func PreviewNotInConfig() ([]SnapshotCandidate, error) {
 		}
 
 		candidates = append(candidates, SnapshotCandidate{
+			Repository:   snapshot.Repository,
 			SnapshotID:   snapshot.SnapshotID,
 			Time:         snapshot.Time.Format(time.RFC3339),
 			BackupID:     snapshot.BackupID,
@@ -58,12 +66,14 @@ func PreviewNotInConfig() ([]SnapshotCandidate, error) {
 	return candidates, nil
 }
 
-func PreviewBackupID(backupID string) ([]SnapshotCandidate, error) {
+// PreviewBackupID lists the snapshots of backupID in one repository. The
+// copies replication keeps in other repositories are left alone.
+func PreviewBackupID(repository string, backupID string) ([]SnapshotCandidate, error) {
 	if _, err := snapshotservice.RefreshSnapshotCache(); err != nil {
 		return nil, err
 	}
 
-	snapshots, err := repo.ListSnapshots()
+	snapshots, err := repo.ListSnapshots(repository)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +85,7 @@ func PreviewBackupID(backupID string) ([]SnapshotCandidate, error) {
 		}
 
 		candidates = append(candidates, SnapshotCandidate{
+			Repository:   snapshot.Repository,
 			SnapshotID:   snapshot.SnapshotID,
 			Time:         snapshot.Time.Format(time.RFC3339),
 			BackupID:     snapshot.BackupID,
@@ -89,28 +100,49 @@ func PreviewBackupID(backupID string) ([]SnapshotCandidate, error) {
 	return candidates, nil
 }
 
-func RunNotInConfig(candidates []SnapshotCandidate) (string, error) {
-	return runDeleteSnapshots("Prune not in config", candidates)
+func RunNotInConfig(logs *taskservice.Log, candidates []SnapshotCandidate) error {
+	return runDeleteSnapshots(logs, "Prune not in config", candidates)
 }
 
-func RunDeleteSnapshot(candidate SnapshotCandidate) (string, error) {
-	return runDeleteSnapshots("Delete snapshot", []SnapshotCandidate{candidate})
+func RunDeleteSnapshot(logs *taskservice.Log, candidate SnapshotCandidate) error {
+	return runDeleteSnapshots(logs, "Delete snapshot", []SnapshotCandidate{candidate})
 }
 
-func RunDeleteBackupID(candidates []SnapshotCandidate) (string, error) {
-	return runDeleteSnapshots("Delete backup ID", candidates)
+// RunDeleteBackupID forgets the candidates in repository. Candidates of
+// other repositories are refused.
+func RunDeleteBackupID(logs *taskservice.Log, repository string, candidates []SnapshotCandidate) error {
+	scoped := make([]SnapshotCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Repository == "" {
+			candidate.Repository = repository
+		}
+		if candidate.Repository != repository {
+			return fmt.Errorf("snapshot %s is in repository %s, not %s", candidate.SnapshotID, candidate.Repository, repository)
+		}
+		scoped = append(scoped, candidate)
+	}
+
+	return runDeleteSnapshots(logs, "Delete backup ID", scoped)
 }
 
-func runDeleteSnapshots(title string, candidates []SnapshotCandidate) (string, error) {
-	var logs strings.Builder
+func runDeleteSnapshots(logs *taskservice.Log, title string, candidates []SnapshotCandidate) error {
 	logs.WriteString(title)
 	logs.WriteString("\n")
 	logs.WriteString(fmt.Sprintf("Snapshots requested: %d\n\n", len(candidates)))
 
-	snapshotIDs := make([]string, 0, len(candidates))
+	byRepository := make(map[string][]string)
+	repositories := make([]string, 0)
 	for _, candidate := range candidates {
-		snapshotIDs = append(snapshotIDs, candidate.SnapshotID)
-		logs.WriteString(fmt.Sprintf("- %s | %s %v | %s | %s\n",
+		repository := candidate.Repository
+		if repository == "" {
+			repository = appconfig.DefaultRepository()
+		}
+		if _, ok := byRepository[repository]; !ok {
+			repositories = append(repositories, repository)
+		}
+		byRepository[repository] = append(byRepository[repository], candidate.SnapshotID)
+		logs.WriteString(fmt.Sprintf("- %s | %s | %s %v | %s | %s\n",
+			repository,
 			candidate.SnapshotID,
 			candidate.VMType,
 			candidate.VMID,
@@ -119,31 +151,52 @@ func runDeleteSnapshots(title string, candidates []SnapshotCandidate) (string, e
 		))
 	}
 
-	if len(snapshotIDs) == 0 {
+	if len(candidates) == 0 {
 		logs.WriteString("\nNo snapshots selected.\n")
-		return logs.String(), nil
+		return nil
 	}
 
-	output, err := restic.DeleteSnapshots(snapshotIDs)
-	if output != "" {
-		logs.WriteString("\nrestic output:\n")
-		logs.WriteString(output)
-		if !strings.HasSuffix(output, "\n") {
-			logs.WriteString("\n")
+	for _, repository := range repositories {
+		if err := deleteFromRepository(logs, repository, byRepository[repository]); err != nil {
+			logs.WriteString("\nDelete failed.\n")
+			return err
 		}
 	}
-	if err != nil {
-		logs.WriteString("\nDelete failed.\n")
-		return logs.String(), err
-	}
 
 	refreshResult, err := cacheservice.RefreshAll()
 	if err != nil {
 		logs.WriteString("\nSnapshots deleted, but cache refresh failed.\n")
-		return logs.String(), err
+		return err
 	}
 
-	logs.WriteString(fmt.Sprintf("\nDeleted snapshots: %d\n", len(snapshotIDs)))
+	logs.WriteString(fmt.Sprintf("\nDeleted snapshots: %d\n", len(candidates)))
 	logs.WriteString(fmt.Sprintf("Cache refresh snapshot count: %d\n", refreshResult.SnapshotCount))
-	return logs.String(), nil
+	return nil
+}
+
+func deleteFromRepository(logs *taskservice.Log, repository string, snapshotIDs []string) error {
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return err
+	}
+
+	var stderr strings.Builder
+	runner = runner.WithStderr(func(line string) {
+		stderr.WriteString(line)
+		stderr.WriteString("\n")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), pruneTimeout)
+	defer cancel()
+
+	output, err := restic.DeleteSnapshots(ctx, runner, snapshotIDs)
+	output += stderr.String()
+	if output != "" {
+		logs.WriteString(fmt.Sprintf("\nrestic output (%s):\n", repository))
+		logs.WriteString(output)
+		if !strings.HasSuffix(output, "\n") {
+			logs.WriteString("\n")
+		}
+	}
+
+	return err
 }
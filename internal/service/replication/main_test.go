@@ -0,0 +1,20 @@
+package replication
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "prostic-replication-test")
+	if err != nil {
+		panic(err)
+	}
+	// snapshots and replications are recorded in the database
+	os.Setenv("PROSTIC_DB_PATH", filepath.Join(dir, "test.db"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,316 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/restic"
+	backupservice "prostic/internal/service/backups"
+	snapshotservice "prostic/internal/service/snapshots"
+	taskservice "prostic/internal/service/tasks"
+	"prostic/internal/util"
+)
+
+const TaskPurposeReplicate = "replicate"
+
+// copyTimeout replaces restic.timeout for each copied backup ID, since the
+// first copy to a remote target can upload the whole backup.
+const copyTimeout = 7 * 24 * time.Hour
+
+const (
+	StatusSuccess = repo.ReplicationStatusSuccess
+	StatusFailed  = "failed"
+)
+
+var ErrUnknownReplication = errors.New("unknown replication")
+
+type TargetStatus struct {
+	Name             string     `json:"name"`
+	Source           string     `json:"source"`
+	Target           string     `json:"target"`
+	PendingBackups   int        `json:"pendingBackups"`
+	OldestPending    *time.Time `json:"oldestPending"`
+	LagSeconds       int64      `json:"lagSeconds"`
+	LastReplicatedAt *time.Time `json:"lastReplicatedAt"`
+	LastBackupID     string     `json:"lastBackupID,omitempty"`
+}
+
+var (
+	schedulerMu  sync.Mutex
+	lastTickKeys = make(map[string]string)
+	logger       = util.GroupLogger("replication")
+)
+
+// Start runs the named replication as a background task. An empty backupID
+// copies every backup that has not reached all targets yet.
+func Start(name string, backupID string) (*models.Task, error) {
+	replication := appconfig.FindReplication(name)
+	if replication == nil {
+		return nil, ErrUnknownReplication
+	}
+
+	return taskservice.StartBackgroundTask(TaskPurposeReplicate, func(task *models.Task, logs *taskservice.Log) error {
+		return Run(logs, *replication, backupID, task.ID)
+	})
+}
+
+// StartAll runs several replications one after another in one background
+// task, since each task needs the runner for itself.
+func StartAll(replications []appconfig.Replication) (*models.Task, error) {
+	return taskservice.StartBackgroundTask(TaskPurposeReplicate, func(task *models.Task, logs *taskservice.Log) error {
+		var errs []error
+		for index, replication := range replications {
+			if index > 0 {
+				logs.WriteString("\n")
+			}
+			if err := Run(logs, replication, "", task.ID); err != nil {
+				logs.WriteString(fmt.Sprintf("Replication %s failed: %v\n", replication.Name, err))
+				errs = append(errs, fmt.Errorf("replication %s: %w", replication.Name, err))
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// AfterBackup starts every replication configured with after_backup once a
+// run has succeeded.
+func AfterBackup(run models.BackupRun) {
+	if run.Status != backupservice.StatusSuccess || appconfig.Get() == nil {
+		return
+	}
+
+	var replications []appconfig.Replication
+	for _, replication := range appconfig.Get().Replications {
+		if replication.AfterBackup {
+			replications = append(replications, replication)
+		}
+	}
+	if len(replications) == 0 {
+		return
+	}
+
+	if _, err := StartAll(replications); err != nil {
+		logger.Warnf("Could not start replication after backup %s: %v", run.BackupID, err)
+	}
+}
+
+func SchedulerTick(now time.Time) {
+	if appconfig.Get() == nil {
+		return
+	}
+
+	var due []appconfig.Replication
+	for _, replication := range appconfig.Get().Replications {
+		if strings.TrimSpace(replication.Cron) == "" {
+			continue
+		}
+
+		matches, err := util.CronMatches(replication.Cron, now)
+		if err != nil || !matches {
+			continue
+		}
+
+		key := now.In(time.Local).Format("2006-01-02 15:04")
+		schedulerMu.Lock()
+		if lastTickKeys[replication.Name] == key {
+			schedulerMu.Unlock()
+			continue
+		}
+		lastTickKeys[replication.Name] = key
+		schedulerMu.Unlock()
+
+		due = append(due, replication)
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	if _, err := StartAll(due); err != nil {
+		logger.Warnf("Could not start scheduled replication: %v", err)
+	}
+}
+
+func Run(logs *taskservice.Log, replication appconfig.Replication, backupID string, taskID uint) error {
+	logs.WriteString(fmt.Sprintf("Replication %s: %s -> %s\n", replication.Name, replication.Source, strings.Join(replication.Targets, ", ")))
+
+	if _, err := snapshotservice.RefreshRepository(replication.Source); err != nil {
+		logs.WriteString("Could not refresh source snapshots.\n")
+		return err
+	}
+
+	backups, err := repo.ListBackupIDs(replication.Source, since(replication))
+	if err != nil {
+		return err
+	}
+	if backupID != "" {
+		backups = filterBackupID(backups, backupID)
+		if len(backups) == 0 {
+			logs.WriteString(fmt.Sprintf("Backup ID %s not found in %s.\n", backupID, replication.Source))
+			return fmt.Errorf("backup ID %s not found", backupID)
+		}
+	}
+
+	var errs []error
+	for _, target := range replication.Targets {
+		if err := replicateTarget(logs, replication, target, backups, taskID); err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", target, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func replicateTarget(logs *taskservice.Log, replication appconfig.Replication, target string, backups []repo.BackupIDSummary, taskID uint) error {
+	logs.WriteString(fmt.Sprintf("\nTarget %s\n", target))
+
+	replicated, err := repo.ListReplicatedBackupIDs(replication.Source, target)
+	if err != nil {
+		return err
+	}
+
+	runner, err := restic.ForCopy(replication.Source, target)
+	if err != nil {
+		return err
+	}
+	runner = runner.WithStderr(func(line string) {
+		logs.WriteString(line)
+		logs.WriteString("\n")
+	})
+
+	copied := 0
+	for _, backup := range backups {
+		if replicated[backup.BackupID] {
+			continue
+		}
+
+		logs.WriteString(fmt.Sprintf("Copying backup %s (%d snapshots)\n", backup.BackupID, backup.SnapshotCount))
+		ctx, cancel := context.WithTimeout(context.Background(), copyTimeout)
+		output, copyErr := restic.Copy(ctx, runner, []string{"id=" + backup.BackupID})
+		cancel()
+		if output != "" {
+			logs.WriteString(output)
+			if !strings.HasSuffix(output, "\n") {
+				logs.WriteString("\n")
+			}
+		}
+
+		record := &models.Replication{
+			Name:          replication.Name,
+			BackupID:      backup.BackupID,
+			Source:        replication.Source,
+			Target:        target,
+			Status:        StatusSuccess,
+			SnapshotCount: backup.SnapshotCount,
+			TaskID:        taskID,
+			BackupTime:    backup.Time,
+			ReplicatedAt:  time.Now(),
+		}
+		if copyErr != nil {
+			record.Status = StatusFailed
+		}
+		if err := repo.CreateReplication(record); err != nil {
+			return err
+		}
+		if copyErr != nil {
+			return copyErr
+		}
+		copied++
+	}
+
+	if copied == 0 {
+		logs.WriteString("Nothing to replicate.\n")
+		return nil
+	}
+
+	logs.WriteString(fmt.Sprintf("Backups replicated: %d\n", copied))
+	if _, err := snapshotservice.RefreshRepository(target); err != nil {
+		logs.WriteString(fmt.Sprintf("Cache refresh of %s failed: %v\n", target, err))
+	}
+
+	return nil
+}
+
+// GetStatus reports per replication target how far it lags behind its
+// source, based on the cached snapshots.
+func GetStatus() ([]TargetStatus, error) {
+	statuses := make([]TargetStatus, 0)
+	if appconfig.Get() == nil {
+		return statuses, nil
+	}
+
+	now := time.Now()
+	for _, replication := range appconfig.Get().Replications {
+		backups, err := repo.ListBackupIDs(replication.Source, since(replication))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, target := range replication.Targets {
+			replicated, err := repo.ListReplicatedBackupIDs(replication.Source, target)
+			if err != nil {
+				return nil, err
+			}
+
+			status := TargetStatus{
+				Name:   replication.Name,
+				Source: replication.Source,
+				Target: target,
+			}
+			for _, backup := range backups {
+				if replicated[backup.BackupID] {
+					continue
+				}
+				status.PendingBackups++
+				if status.OldestPending == nil {
+					oldest := backup.Time
+					status.OldestPending = &oldest
+					status.LagSeconds = int64(now.Sub(oldest).Seconds())
+				}
+			}
+
+			last, err := repo.GetLastReplication(replication.Source, target)
+			if err != nil {
+				return nil, err
+			}
+			if last != nil {
+				status.LastReplicatedAt = &last.ReplicatedAt
+				status.LastBackupID = last.BackupID
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+func ListReplications(limit int) ([]models.Replication, error) {
+	return repo.ListReplications(limit)
+}
+
+func since(replication appconfig.Replication) time.Time {
+	maxAge := replication.MaxAgeDuration()
+	if maxAge <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(-maxAge)
+}
+
+func filterBackupID(backups []repo.BackupIDSummary, backupID string) []repo.BackupIDSummary {
+	for _, backup := range backups {
+		if backup.BackupID == backupID {
+			return []repo.BackupIDSummary{backup}
+		}
+	}
+
+	return nil
+}
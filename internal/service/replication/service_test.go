@@ -0,0 +1,97 @@
+package replication
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/db/repo"
+	"prostic/internal/restic/restictest"
+	taskservice "prostic/internal/service/tasks"
+)
+
+const replicationConfig = `repositories:
+  - name: local
+    env:
+      RESTIC_REPOSITORY: /srv/restic
+      RESTIC_PASSWORD: local-secret
+  - name: offsite
+    env:
+      RESTIC_REPOSITORY: s3:https://s3.example.com/backups
+      RESTIC_PASSWORD: offsite-secret
+replication:
+  - name: offsite
+    source: local
+    targets: [offsite]
+`
+
+// sourceSnapshots is `restic snapshots --json` of the source with one backup
+// ID of two snapshots.
+const sourceSnapshots = `[
+  {"id":"1111111111111111111111111111111111111111111111111111111111111111","time":"2024-05-01T10:00:00+02:00","tags":["id=20240501-100000","vm=100","type=disk"],"paths":["/vm-100-disk-0.raw"],"hostname":"pve"},
+  {"id":"2222222222222222222222222222222222222222222222222222222222222222","time":"2024-05-01T10:05:00+02:00","tags":["id=20240501-100000","vm=100","type=config"],"paths":["/100.conf"],"hostname":"pve"}
+]`
+
+func loadConfig(t *testing.T) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(replicationConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appconfig.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunCopiesWithoutDefaultTimeout(t *testing.T) {
+	loadConfig(t)
+	fake := restictest.Install(t, &restictest.Fake{Respond: func(call restictest.Call) (string, error) {
+		if call.Args[0] == "snapshots" && call.Repository == "local" {
+			return sourceSnapshots, nil
+		}
+		if call.Args[0] == "snapshots" {
+			return "[]", nil
+		}
+		return "", nil
+	}})
+
+	started := time.Now()
+	err := Run(&taskservice.Log{}, *appconfig.FindReplication("offsite"), "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var copies []restictest.Call
+	for _, call := range fake.Calls() {
+		if call.Args[0] == "copy" {
+			copies = append(copies, call)
+		}
+	}
+	if len(copies) != 1 {
+		t.Fatalf("ran %d copies, want 1", len(copies))
+	}
+
+	call := copies[0]
+	if !slices.Equal(call.Args, []string{"copy", "--tag", "id=20240501-100000"}) {
+		t.Errorf("copy args = %v", call.Args)
+	}
+	if call.Repository != "offsite" || call.Env["RESTIC_FROM_REPOSITORY"] != "/srv/restic" || call.Env["RESTIC_FROM_PASSWORD"] != "local-secret" {
+		t.Errorf("copy ran against %s with %v", call.Repository, call.Env)
+	}
+	// a large first copy must not be cut off like a quick command
+	if call.Deadline.IsZero() || call.Deadline.Sub(started) < copyTimeout-time.Minute {
+		t.Errorf("copy deadline in %v, want %v", call.Deadline.Sub(started).Round(time.Minute), copyTimeout)
+	}
+
+	replicated, err := repo.ListReplicatedBackupIDs("local", "offsite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replicated["20240501-100000"] {
+		t.Errorf("backup was not recorded as replicated: %v", replicated)
+	}
+}
@@ -1,20 +1,41 @@
 package repostats
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	appconfig "prostic/internal/config"
 	"prostic/internal/db/models"
 	"prostic/internal/db/repo"
 	"prostic/internal/restic"
 )
 
 func RefreshRepoStatCache() error {
-	stats, err := restic.GetStats()
+	var errs []error
+	for _, repository := range appconfig.RepositoryNames() {
+		if err := RefreshRepository(repository); err != nil {
+			errs = append(errs, fmt.Errorf("repository %s: %w", repository, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func RefreshRepository(repository string) error {
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return err
+	}
+
+	stats, err := restic.GetStats(context.Background(), runner)
 	if err != nil {
 		return err
 	}
 
 	return repo.CreateRepoStat(models.RepoStat{
+		Repository:             repository,
 		TotalSize:              stats.TotalSize,
 		TotalUncompressedSize:  stats.TotalUncompressedSize,
 		CompressionRatio:       stats.CompressionRatio,
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	appconfig "prostic/internal/config"
+	"prostic/internal/restic"
+)
+
+const statusTimeout = 30 * time.Second
+
+const (
+	CompressionAuto = "auto"
+	CompressionMax  = "max"
+	CompressionOff  = "off"
+)
+
+var (
+	ErrUnknownRepository  = errors.New("unknown repository")
+	ErrAlreadyInitialized = errors.New("repository is already initialized")
+	ErrInvalidCompression = errors.New("compression must be auto, max or off")
+	ErrInvalidConfig      = errors.New("invalid repository config")
+)
+
+type InitOptions struct {
+	Repository            string `json:"repository"`
+	Compression           string `json:"compression"`
+	CopyChunkerParamsFrom string `json:"copyChunkerParamsFrom"`
+}
+
+type Status struct {
+	Repository string                  `json:"repository"`
+	Status     restic.RepositoryStatus `json:"status"`
+	Message    string                  `json:"message,omitempty"`
+}
+
+func GetStatus(ctx context.Context, name string) (*Status, error) {
+	repository := appconfig.FindRepository(name)
+	if repository == nil {
+		return nil, ErrUnknownRepository
+	}
+
+	status := &Status{Repository: repository.Name}
+	if err := repository.Validate(); err != nil {
+		status.Status = restic.RepositoryUnreachable
+		status.Message = err.Error()
+		return status, nil
+	}
+
+	runner, err := restic.ForRepository(repository.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	state := restic.GetRepositoryState(ctx, runner)
+	status.Status = state.Status
+	status.Message = state.Message
+	return status, nil
+}
+
+// Init creates the repository after making sure it does not exist yet. The
+// returned string is the restic output, suitable for logs.
+func Init(ctx context.Context, options InitOptions) (string, error) {
+	repository := appconfig.FindRepository(options.Repository)
+	if repository == nil {
+		return "", ErrUnknownRepository
+	}
+	if err := repository.Validate(); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	compression := strings.TrimSpace(options.Compression)
+	if compression == "" {
+		compression = CompressionAuto
+	}
+	initOptions := restic.InitOptions{RepositoryVersion: 2}
+	switch compression {
+	case CompressionAuto, CompressionMax:
+	case CompressionOff:
+		initOptions.RepositoryVersion = 1
+	default:
+		return "", ErrInvalidCompression
+	}
+
+	status, err := GetStatus(ctx, repository.Name)
+	if err != nil {
+		return "", err
+	}
+	if status.Status != restic.RepositoryNotInitialized {
+		if status.Status == restic.RepositoryUnreachable {
+			return "", fmt.Errorf("repository %s is unreachable: %s", repository.Name, status.Message)
+		}
+		return "", ErrAlreadyInitialized
+	}
+
+	var runner restic.Runner
+	if options.CopyChunkerParamsFrom != "" {
+		runner, err = restic.ForCopy(options.CopyChunkerParamsFrom, repository.Name)
+		initOptions.CopyChunkerParams = true
+	} else {
+		runner, err = restic.ForRepository(repository.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var logs strings.Builder
+	runner = runner.WithStderr(func(line string) {
+		logs.WriteString(line)
+		logs.WriteString("\n")
+	})
+
+	output, err := restic.Init(ctx, runner, initOptions)
+	logs.WriteString(output)
+	if err != nil {
+		return logs.String(), err
+	}
+
+	if compression == CompressionMax && repository.Env["RESTIC_COMPRESSION"] != CompressionMax {
+		logs.WriteString("Set RESTIC_COMPRESSION=max in the repository env to back up with maximum compression.\n")
+	}
+
+	return logs.String(), nil
+}
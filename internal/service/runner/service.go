@@ -19,16 +19,22 @@ type Handle struct {
 	released bool
 }
 
+// ChangeHook is called with the new status whenever the runner is taken or
+// released.
+type ChangeHook func(status Status)
+
 var (
 	mu      sync.Mutex
 	current *Status
+
+	hooksMu sync.Mutex
+	hooks   []ChangeHook
 )
 
 func Start(kind string, purpose string) (*Handle, error) {
 	mu.Lock()
-	defer mu.Unlock()
-
 	if current != nil {
+		mu.Unlock()
 		return nil, ErrBusy
 	}
 
@@ -39,20 +45,41 @@ func Start(kind string, purpose string) (*Handle, error) {
 		Purpose:   purpose,
 		StartedAt: &startedAt,
 	}
+	status := *current
+	mu.Unlock()
 
+	notifyChange(status)
 	return &Handle{}, nil
 }
 
 func (h *Handle) Release() {
 	mu.Lock()
-	defer mu.Unlock()
-
 	if h == nil || h.released {
+		mu.Unlock()
 		return
 	}
 
 	current = nil
 	h.released = true
+	mu.Unlock()
+
+	notifyChange(Status{Running: false})
+}
+
+func OnChange(hook ChangeHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, hook)
+}
+
+func notifyChange(status Status) {
+	hooksMu.Lock()
+	registered := append([]ChangeHook(nil), hooks...)
+	hooksMu.Unlock()
+
+	for _, hook := range registered {
+		hook(status)
+	}
 }
 
 func GetStatus() Status {
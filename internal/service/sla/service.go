@@ -0,0 +1,194 @@
+package sla
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	coverageservice "prostic/internal/service/coverage"
+	"prostic/internal/util"
+)
+
+// checkInterval is how often the snapshot cache is evaluated. The cache only
+// changes after backups and refreshes, so there is no need to check on every
+// scheduler tick.
+const checkInterval = 5 * time.Minute
+
+// Alert is the SLA state of one guest. Breached is set when the last
+// complete backup is older than the guest's stale_after, or when there is
+// none at all.
+type Alert struct {
+	VMID          int        `json:"vmid"`
+	Name          string     `json:"name"`
+	Breached      bool       `json:"breached"`
+	Since         *time.Time `json:"since"`
+	LastGoodAt    *time.Time `json:"lastGoodAt"`
+	AgeSeconds    int64      `json:"ageSeconds"`
+	MaxAgeSeconds int64      `json:"maxAgeSeconds"`
+	CheckedAt     time.Time  `json:"checkedAt"`
+}
+
+type ChangeHook func(alert Alert)
+
+var (
+	mu     sync.Mutex
+	states = make(map[int]Alert)
+	// loaded is set once states holds the states stored before a restart
+	loaded    bool
+	lastCheck time.Time
+	hooks     []ChangeHook
+	logger    = util.GroupLogger("sla")
+)
+
+// OnChange registers a hook that is called whenever a guest enters or leaves
+// the breached state.
+func OnChange(hook ChangeHook) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook)
+}
+
+func SchedulerTick(now time.Time) {
+	mu.Lock()
+	due := now.Sub(lastCheck) >= checkInterval
+	if due {
+		lastCheck = now
+	}
+	mu.Unlock()
+
+	if !due {
+		return
+	}
+	if err := Check(now); err != nil {
+		logger.Warnf("Could not check backup SLA: %v", err)
+	}
+}
+
+// AfterBackup re-evaluates right after a run so a recovered guest does not
+// wait for the next interval.
+func AfterBackup(_ models.BackupRun) {
+	if err := Check(time.Now()); err != nil {
+		logger.Warnf("Could not check backup SLA: %v", err)
+	}
+}
+
+// Check evaluates every configured guest against its SLA and notifies hooks
+// about state changes.
+func Check(now time.Time) error {
+	guests, err := coverageservice.List()
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	if !loaded {
+		if err := loadStates(); err != nil {
+			mu.Unlock()
+			return err
+		}
+	}
+
+	var changed []Alert
+	next := make(map[int]Alert, len(guests))
+	for _, guest := range guests {
+		alert := Alert{
+			VMID:          guest.VMID,
+			Name:          guest.Name,
+			LastGoodAt:    guest.LastGoodBackupAt,
+			AgeSeconds:    guest.LastGoodAgeSeconds,
+			MaxAgeSeconds: guest.StaleAfterSeconds,
+			CheckedAt:     now,
+		}
+		alert.Breached = guest.LastGoodBackupAt == nil || guest.LastGoodAgeSeconds > guest.StaleAfterSeconds
+
+		previous, known := states[guest.VMID]
+		if known && previous.Breached == alert.Breached {
+			alert.Since = previous.Since
+		} else {
+			since := now
+			alert.Since = &since
+			if known || alert.Breached {
+				changed = append(changed, alert)
+			}
+		}
+		next[guest.VMID] = alert
+	}
+	states = next
+	if err := saveStates(); err != nil {
+		logger.Warnf("Could not store SLA states: %v", err)
+	}
+	registered := append([]ChangeHook(nil), hooks...)
+	mu.Unlock()
+
+	for _, alert := range changed {
+		if alert.Breached {
+			logger.Warnf("Backup SLA of %s (%d) breached", alert.Name, alert.VMID)
+		} else {
+			logger.Infof("Backup SLA of %s (%d) is met again", alert.Name, alert.VMID)
+		}
+		for _, hook := range registered {
+			hook(alert)
+		}
+	}
+
+	return nil
+}
+
+// loadStates restores the states of the last evaluation before a restart so
+// that only real transitions are reported. The caller holds mu.
+func loadStates() error {
+	stored, err := repo.ListSLAStates()
+	if err != nil {
+		return err
+	}
+
+	for _, state := range stored {
+		since := state.Since
+		states[state.VMID] = Alert{VMID: state.VMID, Breached: state.Breached, Since: &since}
+	}
+	loaded = true
+	return nil
+}
+
+// saveStates stores the current states. The caller holds mu.
+func saveStates() error {
+	stored := make([]models.SLAState, 0, len(states))
+	for _, alert := range states {
+		state := models.SLAState{VMID: alert.VMID, Breached: alert.Breached}
+		if alert.Since != nil {
+			state.Since = *alert.Since
+		}
+		stored = append(stored, state)
+	}
+
+	return repo.ReplaceSLAStates(stored)
+}
+
+// Alerts returns the last evaluated state of every guest, ordered by VM ID.
+func Alerts() []Alert {
+	mu.Lock()
+	defer mu.Unlock()
+
+	alerts := make([]Alert, 0, len(states))
+	for _, alert := range states {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].VMID < alerts[j].VMID
+	})
+
+	return alerts
+}
+
+func Breaches() []Alert {
+	breaches := make([]Alert, 0)
+	for _, alert := range Alerts() {
+		if alert.Breached {
+			breaches = append(breaches, alert)
+		}
+	}
+
+	return breaches
+}
@@ -0,0 +1,100 @@
+package snapshots
+
+import (
+	"context"
+	"time"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/restic"
+)
+
+type Detail struct {
+	models.Snapshot
+	Stats          *models.SnapshotStat `json:"stats"`
+	Files          []restic.Node        `json:"files"`
+	ParentSnapshot *models.Snapshot     `json:"parentSnapshot"`
+	BackupRun      *models.BackupRun    `json:"backupRun"`
+}
+
+// GetDetail loads a cached snapshot together with its stats, file list,
+// parent and the backup run that created it. Stats are computed on first
+// access and cached.
+func GetDetail(ctx context.Context, id uint) (*Detail, error) {
+	snapshot, err := repo.GetSnapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, ErrSnapshotNotFound
+	}
+
+	runner, err := restic.ForRepository(snapshot.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &Detail{Snapshot: *snapshot}
+	detail.Stats, err = getSnapshotStat(ctx, runner, *snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	detail.Files, err = restic.ListFiles(ctx, runner, snapshot.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.Parent != "" {
+		detail.ParentSnapshot, err = repo.FindSnapshot(snapshot.Repository, snapshot.Parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if snapshot.BackupID != "" {
+		detail.BackupRun, err = repo.FindBackupRunByBackupID(snapshot.BackupID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return detail, nil
+}
+
+func getSnapshotStat(ctx context.Context, runner restic.Runner, snapshot models.Snapshot) (*models.SnapshotStat, error) {
+	stat, err := repo.GetSnapshotStat(snapshot.Repository, snapshot.SnapshotID)
+	if err != nil || stat != nil {
+		return stat, err
+	}
+
+	restoreSize, err := restic.GetSnapshotStats(ctx, runner, restic.StatsModeRestoreSize, snapshot.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	rawData, err := restic.GetSnapshotStats(ctx, runner, restic.StatsModeRawData, snapshot.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	stat = &models.SnapshotStat{
+		Repository:          snapshot.Repository,
+		SnapshotID:          snapshot.SnapshotID,
+		RestoreSize:         restoreSize.TotalSize,
+		RestoreFileCount:    restoreSize.TotalFileCount,
+		RawDataSize:         rawData.TotalSize,
+		RawUncompressedSize: rawData.TotalUncompressedSize,
+		RawBlobCount:        rawData.TotalBlobCount,
+		RawCompressionRatio: rawData.CompressionRatio,
+		ComputedAt:          time.Now(),
+	}
+	if err := repo.CreateSnapshotStat(stat); err != nil {
+		// a concurrent request may have stored the same stats first
+		if existing, getErr := repo.GetSnapshotStat(snapshot.Repository, snapshot.SnapshotID); getErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	return stat, nil
+}
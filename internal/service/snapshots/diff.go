@@ -0,0 +1,105 @@
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/restic"
+	"prostic/internal/util"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrNotComparable    = errors.New("snapshots belong to different guests, items or repositories")
+)
+
+type DiffResult struct {
+	From         models.Snapshot `json:"from"`
+	To           models.Snapshot `json:"to"`
+	AddedBytes   int64           `json:"addedBytes"`
+	RemovedBytes int64           `json:"removedBytes"`
+	AddedBlobs   int             `json:"addedBlobs"`
+	RemovedBlobs int             `json:"removedBlobs"`
+	ChangedFiles int             `json:"changedFiles"`
+	TextDiff     string          `json:"textDiff,omitempty"`
+}
+
+// Diff compares two cached snapshots of the same guest item. For config
+// snapshots the file contents are diffed as text as well.
+func Diff(ctx context.Context, fromID uint, toID uint) (*DiffResult, error) {
+	from, err := repo.GetSnapshot(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := repo.GetSnapshot(toID)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil {
+		return nil, ErrSnapshotNotFound
+	}
+	if !sameItem(*from, *to) {
+		return nil, ErrNotComparable
+	}
+
+	runner, err := restic.ForRepository(from.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := restic.Diff(ctx, runner, from.SnapshotID, to.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DiffResult{
+		From:         *from,
+		To:           *to,
+		AddedBytes:   stats.Added.Bytes,
+		RemovedBytes: stats.Removed.Bytes,
+		AddedBlobs:   stats.Added.DataBlobs,
+		RemovedBlobs: stats.Removed.DataBlobs,
+		ChangedFiles: stats.ChangedFiles,
+	}
+
+	if from.SnapshotType == "config" {
+		result.TextDiff, err = configDiff(ctx, runner, *from, *to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func sameItem(from models.Snapshot, to models.Snapshot) bool {
+	if from.VMID == nil || to.VMID == nil || *from.VMID != *to.VMID {
+		return false
+	}
+
+	return from.Repository == to.Repository &&
+		from.SnapshotType == to.SnapshotType &&
+		from.SrcFile == to.SrcFile
+}
+
+func configDiff(ctx context.Context, runner restic.Runner, from models.Snapshot, to models.Snapshot) (string, error) {
+	fromContent, err := restic.Dump(ctx, runner, from.SnapshotID, snapshotPath(from))
+	if err != nil {
+		return "", err
+	}
+	toContent, err := restic.Dump(ctx, runner, to.SnapshotID, snapshotPath(to))
+	if err != nil {
+		return "", err
+	}
+
+	return util.UnifiedDiff(from.BackupID, to.BackupID, fromContent, toContent), nil
+}
+
+// snapshotPath returns where restic stored the item; backups read from stdin
+// are placed at the root under their --stdin-filename.
+func snapshotPath(snapshot models.Snapshot) string {
+	return path.Join("/", snapshot.DestFile)
+}
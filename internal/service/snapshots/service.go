@@ -1,34 +1,91 @@
 package snapshots
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 
+	appconfig "prostic/internal/config"
 	"prostic/internal/db/models"
 	"prostic/internal/db/repo"
 	"prostic/internal/restic"
 )
 
+// RefreshSnapshotCache refreshes every configured repository. A failing
+// repository does not stop the others; all errors are returned joined.
 func RefreshSnapshotCache() (int, error) {
-	snapshots, err := restic.GetSnapshots()
+	repositories := appconfig.RepositoryNames()
+	if err := repo.DeleteSnapshotsOutsideRepositories(repositories); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	var errs []error
+	for _, repository := range repositories {
+		count, err := RefreshRepository(repository)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("repository %s: %w", repository, err))
+			continue
+		}
+		total += count
+	}
+
+	return total, errors.Join(errs...)
+}
+
+// RefreshRepository syncs the cache of repository with the full snapshot
+// list and returns the number of snapshots in the repository.
+func RefreshRepository(repository string) (int, error) {
+	rows, err := listSnapshots(repository, nil)
 	if err != nil {
 		return 0, err
 	}
 
-	rows := make([]models.Snapshot, 0, len(snapshots))
-	for _, snapshot := range snapshots {
-		rows = append(rows, mapSnapshot(snapshot))
+	if _, err := repo.SyncSnapshots(repository, rows); err != nil {
+		return 0, err
 	}
 
-	if err := repo.ReplaceSnapshots(rows); err != nil {
+	return len(rows), nil
+}
+
+// RefreshBackupID only fetches the snapshots of one backup, which is enough
+// after a backup run, and returns how many of them were found.
+func RefreshBackupID(repository string, backupID string) (int, error) {
+	rows, err := listSnapshots(repository, []string{"id=" + backupID})
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := repo.MergeSnapshots(repository, rows); err != nil {
 		return 0, err
 	}
 
 	return len(rows), nil
 }
 
-func mapSnapshot(snapshot restic.Snapshot) models.Snapshot {
+func listSnapshots(repository string, tags []string) ([]models.Snapshot, error) {
+	runner, err := restic.ForRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := restic.GetSnapshotsWithTags(context.Background(), runner, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]models.Snapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		rows = append(rows, mapSnapshot(repository, snapshot))
+	}
+
+	return rows, nil
+}
+
+func mapSnapshot(repository string, snapshot restic.Snapshot) models.Snapshot {
 	tagsJSON, _ := json.Marshal(snapshot.Tags)
 	pathsJSON, _ := json.Marshal(snapshot.Paths)
 	tagMap := parseTags(snapshot.Tags)
@@ -40,11 +97,13 @@ func mapSnapshot(snapshot restic.Snapshot) models.Snapshot {
 		}
 	}
 
-	return models.Snapshot{
+	row := models.Snapshot{
+		Repository:   repository,
 		SnapshotID:   snapshot.ID,
 		Time:         snapshot.Time,
 		Hostname:     snapshot.Hostname,
 		Tree:         snapshot.Tree,
+		Parent:       snapshot.Parent,
 		Paths:        string(pathsJSON),
 		Tags:         string(tagsJSON),
 		BackupID:     tagMap["id"],
@@ -56,6 +115,12 @@ func mapSnapshot(snapshot restic.Snapshot) models.Snapshot {
 		DestFile:     tagMap["destFile"],
 		SrcFile:      tagMap["srcFile"],
 	}
+	if snapshot.Summary != nil {
+		row.Size = snapshot.Summary.TotalBytesProcessed
+		row.DataAdded = snapshot.Summary.DataAdded
+	}
+
+	return row
 }
 
 func parseTags(tags []string) map[string]string {
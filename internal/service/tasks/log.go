@@ -0,0 +1,200 @@
+package tasks
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/util"
+)
+
+// persistInterval is how often new lines of a running task are written to
+// the database.
+const persistInterval = time.Second
+
+var logger = util.GroupLogger("tasks")
+
+// TaskLogHook receives every complete line a background task logs, already
+// redacted, with its line number.
+type TaskLogHook func(taskID uint, seq int, line string)
+
+var logHooks []TaskLogHook
+
+// LogLine is one numbered line of a task log.
+type LogLine struct {
+	Seq  int    `json:"seq"`
+	Line string `json:"line"`
+}
+
+// Log collects the output of a background task. It can be used like a
+// strings.Builder and passes every complete line to the log hooks as soon as
+// it is written. Lines are stored in the database while the task runs and
+// only the last util.MaxLogLines are kept.
+type Log struct {
+	taskID  uint
+	mu      sync.Mutex
+	lines   []LogLine
+	seq     int
+	partial string
+	pending []models.LogLine
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newLog(taskID uint) *Log {
+	return &Log{taskID: taskID}
+}
+
+func (l *Log) WriteString(s string) (int, error) {
+	l.mu.Lock()
+	l.partial += s
+	var added []LogLine
+	if index := strings.LastIndex(l.partial, "\n"); index >= 0 {
+		for _, line := range strings.Split(l.partial[:index], "\n") {
+			added = append(added, l.append(line))
+		}
+		l.partial = l.partial[index+1:]
+	}
+	l.mu.Unlock()
+
+	for _, line := range added {
+		notifyTaskLog(l.taskID, line.Seq, line.Line)
+	}
+
+	return len(s), nil
+}
+
+// append numbers a complete line and queues it for the database. The caller
+// holds l.mu.
+func (l *Log) append(line string) LogLine {
+	l.seq++
+	entry := LogLine{Seq: l.seq, Line: util.TruncateLogLine(util.Redact(line))}
+	l.lines = append(l.lines, entry)
+	if len(l.lines) > util.MaxLogLines {
+		l.lines = append([]LogLine(nil), l.lines[len(l.lines)-util.MaxLogLines:]...)
+	}
+	l.pending = append(l.pending, models.LogLine{
+		Source:    repo.LogSourceTask,
+		SourceID:  l.taskID,
+		Seq:       entry.Seq,
+		Line:      entry.Line,
+		CreatedAt: time.Now(),
+	})
+
+	return entry
+}
+
+// String returns the kept lines and a trailing line that has no newline yet.
+func (l *Log) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var out strings.Builder
+	if len(l.lines) > 0 && l.lines[0].Seq > 1 {
+		out.WriteString(util.DroppedLogLines(l.lines[0].Seq-1) + "\n")
+	}
+	for _, line := range l.lines {
+		out.WriteString(line.Line + "\n")
+	}
+	out.WriteString(l.partial)
+	return out.String()
+}
+
+// Lines returns the kept lines numbered after after.
+func (l *Log) Lines(after int) []LogLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lines := make([]LogLine, 0)
+	for _, line := range l.lines {
+		if line.Seq > after {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// flush emits a trailing line that has no newline yet.
+func (l *Log) flush() {
+	l.mu.Lock()
+	if l.partial == "" {
+		l.mu.Unlock()
+		return
+	}
+	line := l.append(l.partial)
+	l.partial = ""
+	l.mu.Unlock()
+
+	notifyTaskLog(l.taskID, line.Seq, line.Line)
+}
+
+// startPersisting writes queued lines to the database until stopPersisting
+// is called.
+func (l *Log) startPersisting() {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(persistInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				l.persist()
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (l *Log) stopPersisting() {
+	close(l.stop)
+	<-l.done
+	l.persist()
+}
+
+// persist writes the queued lines and removes stored lines that fell out of
+// the kept window.
+func (l *Log) persist() {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	seq := l.seq
+	l.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	if err := repo.CreateLogLines(pending); err != nil {
+		logger.Warnf("failed to store log lines of task %d: %v", l.taskID, err)
+		return
+	}
+	if seq > util.MaxLogLines {
+		if err := repo.DeleteLogLines(repo.LogSourceTask, l.taskID, seq-util.MaxLogLines); err != nil {
+			logger.Warnf("failed to rotate log lines of task %d: %v", l.taskID, err)
+		}
+	}
+}
+
+func OnTaskLog(hook TaskLogHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	logHooks = append(logHooks, hook)
+}
+
+func notifyTaskLog(taskID uint, seq int, line string) {
+	hooksMu.Lock()
+	hooks := append([]TaskLogHook(nil), logHooks...)
+	hooksMu.Unlock()
+
+	for _, hook := range hooks {
+		hook(taskID, seq, line)
+	}
+}
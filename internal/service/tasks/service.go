@@ -3,11 +3,13 @@ package tasks
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"prostic/internal/db/models"
 	"prostic/internal/db/repo"
 	runnerservice "prostic/internal/service/runner"
+	"prostic/internal/util"
 )
 
 const (
@@ -18,6 +20,33 @@ const (
 
 var ErrTaskRunning = runnerservice.ErrBusy
 
+// TaskFinishedHook is called after a background task has been stored with
+// its final status. err is the error the task failed with.
+type TaskFinishedHook func(task models.Task, err error)
+
+type TaskStartedHook func(task models.Task)
+
+// RunningTask is a background task that has not finished yet, with the
+// output it has logged so far.
+type RunningTask struct {
+	Task models.Task `json:"task"`
+	Logs string      `json:"logs"`
+}
+
+var (
+	hooksMu      sync.Mutex
+	taskHooks    []TaskFinishedHook
+	startedHooks []TaskStartedHook
+
+	runningMu sync.Mutex
+	running   = make(map[uint]runningTask)
+)
+
+type runningTask struct {
+	task models.Task
+	logs *Log
+}
+
 type StatusResponse struct {
 	Running   bool       `json:"running"`
 	Kind      string     `json:"kind,omitempty"`
@@ -41,10 +70,13 @@ func StartTask(purpose string) (*models.Task, error) {
 
 func FinishTask(taskID uint, status string, logs string) error {
 	finishedAt := time.Now()
-	return repo.UpdateTask(taskID, status, logs, &finishedAt)
+	return repo.UpdateTask(taskID, status, util.Redact(logs), &finishedAt)
 }
 
-func StartBackgroundTask(purpose string, run func(task *models.Task) (string, error)) (*models.Task, error) {
+// StartBackgroundTask runs run in the background while holding the runner.
+// Everything written to logs is streamed to the log hooks, stored line by
+// line while the task runs and kept as the task logs once run returns.
+func StartBackgroundTask(purpose string, run func(task *models.Task, logs *Log) error) (*models.Task, error) {
 	handle, err := runnerservice.Start("task", purpose)
 	if err != nil {
 		if err == runnerservice.ErrBusy {
@@ -59,27 +91,165 @@ func StartBackgroundTask(purpose string, run func(task *models.Task) (string, er
 		return nil, err
 	}
 
+	taskLog := newLog(task.ID)
+	runningMu.Lock()
+	running[task.ID] = runningTask{task: *task, logs: taskLog}
+	runningMu.Unlock()
+	notifyTaskStarted(*task)
+	taskLog.startPersisting()
+
 	go func(task *models.Task) {
 		defer handle.Release()
 
-		logs, runErr := run(task)
+		runErr := run(task, taskLog)
 		status := StatusSuccess
 		if runErr != nil {
 			status = StatusFailed
-			if !strings.Contains(logs, runErr.Error()) {
+			if logs := taskLog.String(); !strings.Contains(logs, runErr.Error()) {
 				if logs != "" && !strings.HasSuffix(logs, "\n") {
-					logs += "\n"
+					taskLog.WriteString("\n")
 				}
-				logs += fmt.Sprintf("Error: %v\n", runErr)
+				taskLog.WriteString(fmt.Sprintf("Error: %v\n", runErr))
 			}
 		}
+		taskLog.flush()
+		taskLog.stopPersisting()
 
-		_ = FinishTask(task.ID, status, logs)
+		if err := FinishTask(task.ID, status, taskLog.String()); err == nil {
+			if err := repo.DeleteLogLines(repo.LogSourceTask, task.ID, 0); err != nil {
+				logger.Warnf("failed to remove log lines of task %d: %v", task.ID, err)
+			}
+		}
+		runningMu.Lock()
+		delete(running, task.ID)
+		runningMu.Unlock()
+		notifyTaskFinished(*task, status, runErr)
 	}(task)
 
 	return task, nil
 }
 
+// Running returns the background tasks that have not finished yet.
+func Running() []RunningTask {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	tasks := make([]RunningTask, 0, len(running))
+	for _, entry := range running {
+		tasks = append(tasks, RunningTask{Task: entry.task, Logs: util.Redact(entry.logs.String())})
+	}
+
+	return tasks
+}
+
+// GetLines returns a task with the lines it logged after after. isRunning
+// reports whether the task still runs and more lines may follow. task is nil
+// if there is no such task.
+func GetLines(taskID uint, after int) (task *models.Task, lines []LogLine, isRunning bool, err error) {
+	runningMu.Lock()
+	entry, ok := running[taskID]
+	runningMu.Unlock()
+	if ok {
+		runningTask := entry.task
+		return &runningTask, entry.logs.Lines(after), true, nil
+	}
+
+	task, err = repo.GetTask(taskID)
+	if err != nil || task == nil {
+		return nil, nil, false, err
+	}
+
+	lines = make([]LogLine, 0)
+	logs := strings.TrimSuffix(task.Logs, "\n")
+	if logs == "" {
+		return task, lines, false, nil
+	}
+	// keep the numbers the lines had while the task ran
+	stored := strings.Split(logs, "\n")
+	offset := 0
+	if dropped, ok := util.ParseDroppedLogLines(stored[0]); ok {
+		offset = dropped
+		stored = stored[1:]
+	}
+	for index, line := range stored {
+		if seq := offset + index + 1; seq > after {
+			lines = append(lines, LogLine{Seq: seq, Line: line})
+		}
+	}
+
+	return task, lines, false, nil
+}
+
+// RecoverInterrupted marks tasks that were still running when prostic
+// stopped as failed and rebuilds their logs from the stored lines.
+func RecoverInterrupted() error {
+	tasks, err := repo.ListTasksByStatus(StatusRunning)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		lines, err := repo.ListLogLines(repo.LogSourceTask, task.ID)
+		if err != nil {
+			return err
+		}
+
+		var logs strings.Builder
+		if len(lines) > 0 && lines[0].Seq > 1 {
+			logs.WriteString(util.DroppedLogLines(lines[0].Seq-1) + "\n")
+		}
+		for _, line := range lines {
+			logs.WriteString(line.Line + "\n")
+		}
+		logs.WriteString("Error: interrupted, prostic stopped while the task was running\n")
+
+		if err := FinishTask(task.ID, StatusFailed, logs.String()); err != nil {
+			return err
+		}
+		if err := repo.DeleteLogLines(repo.LogSourceTask, task.ID, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func OnTaskStarted(hook TaskStartedHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	startedHooks = append(startedHooks, hook)
+}
+
+func notifyTaskStarted(task models.Task) {
+	hooksMu.Lock()
+	hooks := append([]TaskStartedHook(nil), startedHooks...)
+	hooksMu.Unlock()
+
+	for _, hook := range hooks {
+		hook(task)
+	}
+}
+
+func OnTaskFinished(hook TaskFinishedHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	taskHooks = append(taskHooks, hook)
+}
+
+func notifyTaskFinished(task models.Task, status string, err error) {
+	finishedAt := time.Now()
+	task.Status = status
+	task.FinishedAt = &finishedAt
+
+	hooksMu.Lock()
+	hooks := append([]TaskFinishedHook(nil), taskHooks...)
+	hooksMu.Unlock()
+
+	for _, hook := range hooks {
+		hook(task, err)
+	}
+}
+
 func GetStatus() StatusResponse {
 	status := runnerservice.GetStatus()
 	return StatusResponse{
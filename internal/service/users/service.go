@@ -0,0 +1,211 @@
+package users
+
+import (
+	"errors"
+	"strings"
+
+	"prostic/internal/db/models"
+	"prostic/internal/db/repo"
+	"prostic/internal/util"
+)
+
+// Roles in increasing order of what they may do. Viewers only read,
+// operators also run backups and tasks, admins also manage keys and users.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// DefaultUsername is the user created from the password prostic had before
+// user accounts. Logins without a username use it.
+const DefaultUsername = "admin"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUnknownUser        = errors.New("unknown user")
+	ErrUserExists         = errors.New("username already exists")
+	ErrInvalidUsername    = errors.New("username must not be empty or contain spaces")
+	ErrInvalidRole        = errors.New("role must be admin, operator or viewer")
+	ErrEmptyPassword      = errors.New("password is required")
+	ErrLastAdmin          = errors.New("the last admin cannot be removed or demoted")
+)
+
+var roleRanks = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Allows reports whether role grants at least the rights of required.
+func Allows(role string, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Update changes the role and password of a user; empty fields are kept.
+type Update struct {
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+// Authenticate returns the user the credentials belong to.
+func Authenticate(username string, password string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		username = DefaultUsername
+	}
+
+	user, err := repo.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := util.CheckPassword(user.PasswordHash, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+func Get(userID uint) (*models.User, error) {
+	return repo.GetUser(userID)
+}
+
+func List() ([]models.User, error) {
+	users, err := repo.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+
+	return users, nil
+}
+
+// Create adds a user that has to change the password on the first login.
+func Create(username string, password string, role string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || strings.ContainsAny(username, " \t\r\n") {
+		return nil, ErrInvalidUsername
+	}
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if strings.TrimSpace(password) == "" {
+		return nil, ErrEmptyPassword
+	}
+
+	existing, err := repo.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrUserExists
+	}
+
+	passwordHash, err := util.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:            username,
+		PasswordHash:        passwordHash,
+		Role:                role,
+		NeedsPasswordChange: true,
+	}
+	if err := repo.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Modify applies update to a user. A password set by an admin has to be
+// changed by the user on the next login.
+func Modify(userID uint, update Update) (*models.User, error) {
+	user, err := repo.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUnknownUser
+	}
+
+	updates := make(map[string]interface{})
+	if update.Role != "" && update.Role != user.Role {
+		if !ValidRole(update.Role) {
+			return nil, ErrInvalidRole
+		}
+		updates["role"] = update.Role
+	}
+	if update.Password != "" {
+		if strings.TrimSpace(update.Password) == "" {
+			return nil, ErrEmptyPassword
+		}
+		passwordHash, err := util.HashPassword(update.Password)
+		if err != nil {
+			return nil, err
+		}
+		updates["password_hash"] = passwordHash
+		updates["needs_password_change"] = true
+	}
+	if len(updates) == 0 {
+		return user, nil
+	}
+
+	if err := keepAdmin(repo.UpdateUserKeepingRole(userID, updates, RoleAdmin)); err != nil {
+		return nil, err
+	}
+
+	return repo.GetUser(userID)
+}
+
+func Delete(userID uint) error {
+	user, err := repo.GetUser(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUnknownUser
+	}
+
+	return keepAdmin(repo.DeleteUserKeepingRole(userID, RoleAdmin))
+}
+
+// ChangePassword sets the password of a user who is logged in.
+func ChangePassword(userID uint, password string) error {
+	if strings.TrimSpace(password) == "" {
+		return ErrEmptyPassword
+	}
+
+	passwordHash, err := util.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return repo.UpdateUser(userID, map[string]interface{}{
+		"password_hash":         passwordHash,
+		"needs_password_change": false,
+	})
+}
+
+// keepAdmin reports a change that was rolled back because it would have left
+// prostic without an admin. The check runs in the same transaction as the
+// change, so concurrent requests cannot remove the last admin together.
+func keepAdmin(err error) error {
+	if errors.Is(err, repo.ErrLastWithRole) {
+		return ErrLastAdmin
+	}
+
+	return err
+}
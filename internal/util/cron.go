@@ -1,4 +1,4 @@
-package backups
+package util
 
 import (
 	"fmt"
@@ -7,7 +7,8 @@ import (
 	"time"
 )
 
-func cronMatches(expression string, now time.Time) (bool, error) {
+// CronMatches reports whether a five-field cron expression matches now.
+func CronMatches(expression string, now time.Time) (bool, error) {
 	fields := strings.Fields(strings.TrimSpace(expression))
 	if len(fields) != 5 {
 		return false, fmt.Errorf("invalid cron expression")
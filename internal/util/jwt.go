@@ -17,28 +17,43 @@ var (
 	jwtSecretErr  error
 )
 
-func CreateJWT() (string, error) {
+// Claims identify the user a token was issued to.
+type Claims struct {
+	UserID   uint   `json:"uid"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func CreateJWT(userID uint, username string, role string) (string, error) {
 	secret, err := getJWTSecret()
 	if err != nil {
 		return "", err
 	}
 
 	now := time.Now()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(30 * time.Minute)),
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID:   userID,
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(30 * time.Minute)),
+		},
 	})
 
 	return token.SignedString(secret)
 }
 
-func ValidateJWT(tokenString string) error {
+func ValidateJWT(tokenString string) (*Claims, error) {
 	secret, err := getJWTSecret()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	parsedToken, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+	claims := &Claims{}
+	parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodHS256 {
 			return nil, errors.New("unexpected signing method")
 		}
@@ -46,13 +61,17 @@ func ValidateJWT(tokenString string) error {
 		return secret, nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !parsedToken.Valid {
-		return errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+	// tokens from before user accounts carry no user
+	if claims.UserID == 0 {
+		return nil, errors.New("token has no user")
 	}
 
-	return nil
+	return claims, nil
 }
 
 func getJWTSecret() ([]byte, error) {
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 )
@@ -31,6 +32,45 @@ type customFormatter struct {
 func (f *customFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	level := entry.Level.String()
-	msg := entry.Message
+	msg := Redact(entry.Message)
 	return []byte(fmt.Sprintf("[%s] [%s] [%s] %s\n", timestamp, f.group, level, msg)), nil
 }
+
+const (
+	// MaxLogLines is how many lines of a task or backup run are kept. Older
+	// lines are dropped once a log grows past it.
+	MaxLogLines = 5000
+	// MaxLogLineLength cuts single lines such as a dumped JSON blob.
+	MaxLogLineLength = 4096
+)
+
+// TruncateLogLine shortens a line to at most MaxLogLineLength bytes without
+// splitting a UTF-8 character.
+func TruncateLogLine(line string) string {
+	if len(line) <= MaxLogLineLength {
+		return line
+	}
+
+	cut := MaxLogLineLength
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+
+	return line[:cut] + " [truncated]"
+}
+
+// DroppedLogLines is the note put in front of a log whose oldest lines were
+// dropped.
+func DroppedLogLines(count int) string {
+	return fmt.Sprintf("[%d earlier lines dropped]", count)
+}
+
+// ParseDroppedLogLines reads the count back from a DroppedLogLines note.
+func ParseDroppedLogLines(line string) (int, bool) {
+	var count int
+	if _, err := fmt.Sscanf(line, "[%d earlier lines dropped]", &count); err != nil {
+		return 0, false
+	}
+
+	return count, true
+}
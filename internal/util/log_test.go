@@ -0,0 +1,58 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateLogLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "short", line: "done", want: "done"},
+		{name: "at the limit", line: strings.Repeat("a", MaxLogLineLength), want: strings.Repeat("a", MaxLogLineLength)},
+		{
+			name: "ascii",
+			line: strings.Repeat("a", MaxLogLineLength+10),
+			want: strings.Repeat("a", MaxLogLineLength) + " [truncated]",
+		},
+		{
+			// the two byte "é" starts one byte before the limit
+			name: "two byte rune across the limit",
+			line: strings.Repeat("a", MaxLogLineLength-1) + "é" + "b",
+			want: strings.Repeat("a", MaxLogLineLength-1) + " [truncated]",
+		},
+		{
+			// the four byte emoji starts two bytes before the limit
+			name: "four byte rune across the limit",
+			line: strings.Repeat("a", MaxLogLineLength-2) + "🚨" + "b",
+			want: strings.Repeat("a", MaxLogLineLength-2) + " [truncated]",
+		},
+		{
+			name: "rune ending at the limit",
+			line: strings.Repeat("a", MaxLogLineLength-2) + "é" + "b",
+			want: strings.Repeat("a", MaxLogLineLength-2) + "é" + " [truncated]",
+		},
+		{
+			name: "only multibyte runes",
+			line: strings.Repeat("ü", MaxLogLineLength),
+			want: strings.Repeat("ü", MaxLogLineLength/2) + " [truncated]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := TruncateLogLine(test.line)
+			if got != test.want {
+				t.Errorf("TruncateLogLine() = %d bytes ending in %q, want %d bytes ending in %q",
+					len(got), got[max(0, len(got)-16):], len(test.want), test.want[max(0, len(test.want)-16):])
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("TruncateLogLine() returned invalid UTF-8")
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minSecretLength keeps short values such as "1" or "yes" from being
+// replaced all over the logs.
+const minSecretLength = 4
+
+const redacted = "[redacted]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// SetSecrets replaces the values Redact hides.
+func SetSecrets(values []string) {
+	seen := make(map[string]bool, len(values))
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if len(value) < minSecretLength || seen[value] {
+			continue
+		}
+		seen[value] = true
+		list = append(list, value)
+	}
+
+	// longer values first so a secret containing another one is hidden as a
+	// whole
+	sort.Slice(list, func(i, j int) bool {
+		return len(list[i]) > len(list[j])
+	})
+
+	secretsMu.Lock()
+	secrets = list
+	secretsMu.Unlock()
+}
+
+// Redact hides every known secret in s.
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	for _, secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+
+	return s
+}
+
+// RedactWith hides the known secrets and values in s, for output produced
+// before values are registered with SetSecrets.
+func RedactWith(s string, values ...string) string {
+	for _, value := range values {
+		if len(value) >= minSecretLength {
+			s = strings.ReplaceAll(s, value, redacted)
+		}
+	}
+
+	return Redact(s)
+}
@@ -0,0 +1,15 @@
+package util
+
+import (
+	"encoding/json"
+	"text/template"
+)
+
+// TemplateFuncs are available in notification templates; json quotes a
+// value so it can be embedded in a JSON document.
+var TemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
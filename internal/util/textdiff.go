@@ -0,0 +1,138 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around a change.
+const diffContext = 3
+
+// noNewline marks a last line without a newline so that it differs from the
+// same line with one.
+const noNewline = "\x00"
+
+type diffLine struct {
+	kind byte
+	text string
+	// number of lines of a and b before this line
+	aIndex int
+	bIndex int
+}
+
+// UnifiedDiff returns a unified diff of two texts, or an empty string when
+// they are equal. It builds a full LCS table and is meant for small files
+// such as guest configs.
+func UnifiedDiff(fromName string, toName string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+	for i := 0; i < len(lines); i++ {
+		if lines[i].kind == ' ' {
+			continue
+		}
+
+		start := max(0, i-diffContext)
+		last := i
+		// changes up to 2*diffContext unchanged lines apart share a hunk
+		for j := i; j < len(lines) && j-last <= 2*diffContext+1; j++ {
+			if lines[j].kind != ' ' {
+				last = j
+			}
+		}
+		end := min(len(lines), last+diffContext+1)
+
+		writeHunk(&out, lines[start:end])
+		i = end - 1
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, hunk []diffLine) {
+	aCount, bCount := 0, 0
+	for _, line := range hunk {
+		if line.kind != '+' {
+			aCount++
+		}
+		if line.kind != '-' {
+			bCount++
+		}
+	}
+
+	aStart, bStart := hunk[0].aIndex, hunk[0].bIndex
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+
+	out.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount))
+	for _, line := range hunk {
+		out.WriteByte(line.kind)
+		if text, ok := strings.CutSuffix(line.text, noNewline); ok {
+			out.WriteString(text)
+			out.WriteString("\n\\ No newline at end of file\n")
+			continue
+		}
+		out.WriteString(line.text)
+		out.WriteByte('\n')
+	}
+}
+
+func diffLines(a []string, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{kind: ' ', text: a[i], aIndex: i, bIndex: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{kind: '-', text: a[i], aIndex: i, bIndex: j})
+			i++
+		default:
+			lines = append(lines, diffLine{kind: '+', text: b[j], aIndex: i, bIndex: j})
+			j++
+		}
+	}
+
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	trimmed, hasNewline := strings.CutSuffix(text, "\n")
+	lines := strings.Split(trimmed, "\n")
+	if !hasNewline {
+		lines[len(lines)-1] += noNewline
+	}
+
+	return lines
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines l1..ln with the replacements applied.
+func numbered(n int, replace map[int]string) string {
+	var out strings.Builder
+	for i := 1; i <= n; i++ {
+		line := fmt.Sprintf("l%d", i)
+		if replacement, ok := replace[i]; ok {
+			line = replacement
+		}
+		out.WriteString(line + "\n")
+	}
+
+	return out.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "both empty",
+			want: "",
+		},
+		{
+			name: "from empty",
+			to:   "a\nb\n",
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			from: "a\nb\n",
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "insert only",
+			from: "a\nb\nc\n",
+			to:   "a\nb\nx\nc\n",
+			want: "@@ -1,3 +1,4 @@\n a\n b\n+x\n c\n",
+		},
+		{
+			name: "delete only",
+			from: "a\nb\nc\n",
+			to:   "a\nc\n",
+			want: "@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name: "context trimmed",
+			from: numbered(10, nil),
+			to:   numbered(10, map[int]string{5: "x"}),
+			want: "@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+x\n l6\n l7\n l8\n",
+		},
+		{
+			name: "hunks merged across six unchanged lines",
+			from: numbered(20, nil),
+			to:   numbered(20, map[int]string{2: "x", 9: "y"}),
+			want: "@@ -1,12 +1,12 @@\n l1\n-l2\n+x\n l3\n l4\n l5\n l6\n l7\n l8\n-l9\n+y\n l10\n l11\n l12\n",
+		},
+		{
+			name: "hunks split across seven unchanged lines",
+			from: numbered(20, nil),
+			to:   numbered(20, map[int]string{2: "x", 10: "y"}),
+			want: "@@ -1,5 +1,5 @@\n l1\n-l2\n+x\n l3\n l4\n l5\n" +
+				"@@ -7,7 +7,7 @@\n l7\n l8\n l9\n-l10\n+y\n l11\n l12\n l13\n",
+		},
+		{
+			name: "trailing newline removed",
+			from: "a\nb\n",
+			to:   "a\nb",
+			want: "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name: "no trailing newline on both sides",
+			from: "a",
+			to:   "b",
+			want: "@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := test.want
+			if want != "" {
+				want = "--- from\n+++ to\n" + want
+			}
+
+			if got := UnifiedDiff("from", "to", test.from, test.to); got != want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}